@@ -0,0 +1,249 @@
+package actionsclient
+
+import (
+	"errors"
+	"fmt"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-common/util/expression"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-client/restclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+type Aggregation string
+
+const (
+	AggregationAll    Aggregation = "all"
+	AggregationAny    Aggregation = "any"
+	AggregationQuorum Aggregation = "quorum"
+)
+
+// ActionOutcomeAbandoned the action was running when the fan-out ended because of the deadline or of a failure. Its call is not
+// interrupted: it runs to completion, its side effects may still happen and only its result is discarded.
+const ActionOutcomeAbandoned ActionOutcome = "abandoned"
+
+var (
+	ErrFanOutNotPassed        = errors.New("fan-out aggregation not satisfied")
+	ErrFanOutDeadlineExceeded = errors.New("fan-out deadline exceeded")
+	ErrFanOutStoppedOnFailure = errors.New("fan-out stopped on failure")
+)
+
+type FanOutOptions struct {
+	Timeout         time.Duration
+	Aggregation     Aggregation
+	Quorum          int
+	CancelOnFailure bool
+	MaxConcurrency  int
+	Merge           MergeStrategy
+}
+
+type FanOutOption func(opts *FanOutOptions)
+
+// FanOutWithTimeout the deadline shared by all the actions. The rest timeout of each call is bounded by the time left and no action
+// is started once the deadline has passed.
+func FanOutWithTimeout(to time.Duration) FanOutOption {
+	return func(opts *FanOutOptions) {
+		opts.Timeout = to
+	}
+}
+
+func FanOutWithAggregation(a Aggregation) FanOutOption {
+	return func(opts *FanOutOptions) {
+		opts.Aggregation = a
+	}
+}
+
+// FanOutWithQuorum at least n actions have to succeed.
+func FanOutWithQuorum(n int) FanOutOption {
+	return func(opts *FanOutOptions) {
+		opts.Aggregation = AggregationQuorum
+		opts.Quorum = n
+	}
+}
+
+// FanOutWithCancelOnFailure the first failure ends the fan-out: actions not yet started are not executed and the running ones are abandoned.
+// Abandoned calls are not interrupted, they complete in background and their side effects still happen.
+func FanOutWithCancelOnFailure(b bool) FanOutOption {
+	return func(opts *FanOutOptions) {
+		opts.CancelOnFailure = b
+	}
+}
+
+// FanOutWithMaxConcurrency limits the number of actions running at the same time, all of them if not positive.
+func FanOutWithMaxConcurrency(n int) FanOutOption {
+	return func(opts *FanOutOptions) {
+		opts.MaxConcurrency = n
+	}
+}
+
+func FanOutWithMergeStrategy(s MergeStrategy) FanOutOption {
+	return func(opts *FanOutOptions) {
+		opts.Merge = s
+	}
+}
+
+// FanOutReport the outcome of a fan-out: the report of each action in the order given, the number of successful ones and whether
+// the aggregation is satisfied. Body is the input body with the responses of the successful enrich actions merged in the order given,
+// regardless of the completion order.
+type FanOutReport struct {
+	Body      map[string]interface{} `yaml:"body,omitempty" mapstructure:"body,omitempty" json:"body,omitempty"`
+	Actions   []ActionReport         `yaml:"actions,omitempty" mapstructure:"actions,omitempty" json:"actions,omitempty"`
+	Succeeded int                    `yaml:"succeeded" mapstructure:"succeeded" json:"succeeded"`
+	Passed    bool                   `yaml:"passed" mapstructure:"passed" json:"passed"`
+}
+
+func (r *FanOutReport) Err() error {
+	for _, a := range r.Actions {
+		if a.Outcome == ActionOutcomeFailed || a.Outcome == ActionOutcomeAbandoned {
+			return a.Err
+		}
+	}
+
+	return nil
+}
+
+// FanOut executes a set of independent actions concurrently.
+type FanOut struct {
+	caller ActionCaller
+	opts   FanOutOptions
+}
+
+func NewFanOut(caller ActionCaller, opts ...FanOutOption) *FanOut {
+	fOpts := FanOutOptions{Aggregation: AggregationAll, Merge: MergeDeep}
+	for _, o := range opts {
+		o(&fOpts)
+	}
+
+	return &FanOut{caller: caller, opts: fOpts}
+}
+
+func (lks *LinkedService) NewFanOut(opts ...FanOutOption) *FanOut {
+	return NewFanOut(lks, opts...)
+}
+
+type fanOutResult struct {
+	ndx      int
+	resp     map[string]interface{}
+	err      error
+	duration time.Duration
+}
+
+// Execute runs the actions, each one with the body plus its own properties, and waits for their completion, the deadline or, if
+// configured, the first failure. The report is always returned; the error is ErrFanOutNotPassed if the aggregation is not satisfied.
+func (f *FanOut) Execute(acts []token.Action, expressionCtx *expression.Context, body map[string]interface{}, opts ...restclient.Option) (*FanOutReport, error) {
+	const semLogContext = semLogContextBase + "::fan-out-execute"
+
+	if f.opts.Aggregation == AggregationQuorum && f.opts.Quorum <= 0 {
+		return nil, fmt.Errorf("invalid fan-out quorum: %d", f.opts.Quorum)
+	}
+
+	report := &FanOutReport{Actions: make([]ActionReport, len(acts))}
+	for i, act := range acts {
+		report.Actions[i] = ActionReport{ActionId: act.ActionId, Merge: f.opts.Merge, Outcome: ActionOutcomeNotExecuted}
+	}
+
+	maxConcurrency := f.opts.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = len(acts)
+	}
+
+	var deadline time.Time
+	var timeout <-chan time.Time
+	if f.opts.Timeout > 0 {
+		deadline = time.Now().Add(f.opts.Timeout)
+		timer := time.NewTimer(f.opts.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	// buffered so that the abandoned actions complete without blocking.
+	results := make(chan fanOutResult, len(acts))
+	running := make(map[int]struct{})
+	next := 0
+	launch := func() {
+		for next < len(acts) && len(running) < maxConcurrency {
+			callOpts := opts
+			if !deadline.IsZero() {
+				// a non positive timeout means no timeout at all for the rest client.
+				left := time.Until(deadline)
+				if left <= 0 {
+					return
+				}
+				callOpts = append(append([]restclient.Option{}, opts...), restclient.WithTimeout(left))
+			}
+
+			go func(ndx int, act token.Action, callOpts []restclient.Option) {
+				start := time.Now()
+				resp, err := f.caller.CallAction(act.ActionId, expressionCtx, actionBody(body, act.Properties), callOpts...)
+				results <- fanOutResult{ndx: ndx, resp: resp, err: err, duration: time.Since(start)}
+			}(next, acts[next], callOpts)
+
+			running[next] = struct{}{}
+			next++
+		}
+	}
+
+	var stopErr error
+	launch()
+	for len(running) > 0 && stopErr == nil {
+		select {
+		case r := <-results:
+			delete(running, r.ndx)
+			ar := &report.Actions[r.ndx]
+			ar.Duration = r.duration
+			if r.err != nil {
+				log.Error().Err(r.err).Str("action-id", ar.ActionId).Msg(semLogContext)
+				ar.Outcome, ar.Err, ar.Error = ActionOutcomeFailed, r.err, r.err.Error()
+				if f.opts.CancelOnFailure {
+					stopErr = ErrFanOutStoppedOnFailure
+				}
+			} else {
+				ar.Outcome, ar.Response = ActionOutcomeOk, r.resp
+				report.Succeeded++
+			}
+
+			if stopErr == nil {
+				launch()
+			}
+		case <-timeout:
+			log.Warn().Int("running", len(running)).Msg(semLogContext + " deadline exceeded")
+			stopErr = ErrFanOutDeadlineExceeded
+		}
+	}
+
+	if stopErr == nil && next < len(acts) {
+		// nothing left running and the deadline passed before the remaining actions could be started.
+		log.Warn().Int("not-executed", len(acts)-next).Msg(semLogContext + " deadline exceeded")
+		stopErr = ErrFanOutDeadlineExceeded
+	}
+
+	for ndx := range running {
+		ar := &report.Actions[ndx]
+		ar.Outcome, ar.Err, ar.Error = ActionOutcomeAbandoned, stopErr, stopErr.Error()
+	}
+
+	report.Body = copyMap(body)
+	for _, ar := range report.Actions {
+		if ar.Outcome == ActionOutcomeOk {
+			report.Body = MergeResponse(f.opts.Merge, ar.ActionId, report.Body, ar.Response)
+		}
+	}
+
+	report.Passed = f.passed(report.Succeeded, len(acts))
+	if !report.Passed {
+		return report, fmt.Errorf("%w: %d of %d actions succeeded (%s)", ErrFanOutNotPassed, report.Succeeded, len(acts), f.opts.Aggregation)
+	}
+
+	return report, nil
+}
+
+func (f *FanOut) passed(succeeded, total int) bool {
+	switch f.opts.Aggregation {
+	case AggregationAny:
+		return succeeded > 0
+	case AggregationQuorum:
+		return succeeded >= f.opts.Quorum
+	}
+
+	return succeeded == total
+}
@@ -0,0 +1,83 @@
+package actionsclient_test
+
+import (
+	"errors"
+	actions "github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/actionsclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestFanOut(t *testing.T) {
+
+	caller := callerFunc(func(actionId string, body map[string]interface{}) (map[string]interface{}, error) {
+		switch actionId {
+		case "eligibility":
+			time.Sleep(30 * time.Millisecond)
+			return map[string]interface{}{"segment": "gold", "limits": map[string]interface{}{"daily": 1}}, nil
+		case "budget":
+			return map[string]interface{}{"segment": "silver", "limits": map[string]interface{}{"monthly": 10}}, nil
+		case "blacklist":
+			time.Sleep(10 * time.Millisecond)
+			return nil, nil
+		case "slow":
+			time.Sleep(500 * time.Millisecond)
+			return nil, nil
+		}
+		return nil, errors.New("check failed")
+	})
+
+	input := map[string]interface{}{"ctx-id": "c1"}
+	acts := []token.Action{{ActionId: "eligibility"}, {ActionId: "blacklist"}, {ActionId: "budget"}}
+
+	report, err := actions.NewFanOut(caller).Execute(acts, nil, input)
+	require.NoError(t, err)
+	require.True(t, report.Passed)
+	require.Equal(t, 3, report.Succeeded)
+	// merged in the order given: budget overrides eligibility even if it completed first.
+	require.Equal(t, "silver", report.Body["segment"])
+	require.Equal(t, map[string]interface{}{"daily": 1, "monthly": 10}, report.Body["limits"])
+	require.Equal(t, "c1", report.Body["ctx-id"])
+
+	acts = []token.Action{{ActionId: "blacklist"}, {ActionId: "failing"}, {ActionId: "budget"}}
+	report, err = actions.NewFanOut(caller).Execute(acts, nil, input)
+	require.ErrorIs(t, err, actions.ErrFanOutNotPassed)
+	require.Equal(t, 2, report.Succeeded)
+	require.Equal(t, actions.ActionOutcomeFailed, report.Actions[1].Outcome)
+
+	report, err = actions.NewFanOut(caller, actions.FanOutWithAggregation(actions.AggregationAny)).Execute(acts, nil, input)
+	require.NoError(t, err)
+	require.True(t, report.Passed)
+
+	report, err = actions.NewFanOut(caller, actions.FanOutWithQuorum(3)).Execute(acts, nil, input)
+	require.ErrorIs(t, err, actions.ErrFanOutNotPassed)
+
+	acts = []token.Action{{ActionId: "blacklist"}, {ActionId: "slow"}}
+	start := time.Now()
+	report, err = actions.NewFanOut(caller, actions.FanOutWithTimeout(100*time.Millisecond)).Execute(acts, nil, input)
+	require.ErrorIs(t, err, actions.ErrFanOutNotPassed)
+	require.Less(t, time.Since(start), 400*time.Millisecond)
+	require.Equal(t, actions.ActionOutcomeOk, report.Actions[0].Outcome)
+	require.Equal(t, actions.ActionOutcomeAbandoned, report.Actions[1].Outcome)
+	require.ErrorIs(t, report.Err(), actions.ErrFanOutDeadlineExceeded)
+
+	acts = []token.Action{{ActionId: "failing"}, {ActionId: "slow"}, {ActionId: "budget"}}
+	report, err = actions.NewFanOut(caller, actions.FanOutWithCancelOnFailure(true), actions.FanOutWithMaxConcurrency(2)).Execute(acts, nil, input)
+	require.ErrorIs(t, err, actions.ErrFanOutNotPassed)
+	require.Equal(t, actions.ActionOutcomeFailed, report.Actions[0].Outcome)
+	require.Equal(t, actions.ActionOutcomeAbandoned, report.Actions[1].Outcome)
+	// max concurrency 2: the third action was never started.
+	require.Equal(t, actions.ActionOutcomeNotExecuted, report.Actions[2].Outcome)
+
+	// deadline already passed: no action is started, not even with an unbounded rest timeout.
+	called := false
+	report, err = actions.NewFanOut(callerFunc(func(actionId string, body map[string]interface{}) (map[string]interface{}, error) {
+		called = true
+		return nil, nil
+	}), actions.FanOutWithTimeout(time.Nanosecond)).Execute(acts, nil, input)
+	require.ErrorIs(t, err, actions.ErrFanOutNotPassed)
+	require.False(t, called)
+	require.Equal(t, 0, report.Succeeded)
+	require.Equal(t, actions.ActionOutcomeNotExecuted, report.Actions[0].Outcome)
+}
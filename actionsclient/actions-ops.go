@@ -2,7 +2,9 @@ package actionsclient
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-common/util"
 	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-common/util/expression"
 	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-archive/har"
 	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-client/restclient"
@@ -31,6 +33,9 @@ func (lks *LinkedService) NewClientById(id string, expressionCtx *expression.Con
 	return client, err
 }
 
+// NewClient resolves path and headers of the action against the expression context. The underlying rest client is taken from the
+// pool of the linked service: spans given as options are used as defaults of the requests and a timeout different from the configured
+// one bounds each request. A trace group name gets a dedicated rest client, holding the group span until Close.
 func (lks *LinkedService) NewClient(cfg Config, expressionCtx *expression.Context, opts ...restclient.Option) (*Client, error) {
 	const semLogContext = semLogContextBase + "::new"
 
@@ -43,45 +48,134 @@ func (lks *LinkedService) NewClient(cfg Config, expressionCtx *expression.Contex
 		resolvedCfg.Path = fmt.Sprint(v)
 	}
 
-	resolvedCfg.Headers = nil
+	var headers []har.NameValuePair
 	for i := range cfg.Headers {
 		if cfg.Headers[i].Value == "uuid" {
-			resolvedCfg.Headers = append(resolvedCfg.Headers, restclient.Header{Name: cfg.Headers[i].Name, Value: uuid.New().String()})
+			headers = append(headers, har.NameValuePair{Name: cfg.Headers[i].Name, Value: uuid.New().String()})
 		} else {
 			if expressionCtx != nil {
 				v, err := expressionCtx.EvalOne(cfg.Headers[i].Value)
 				if err != nil {
 					return nil, err
 				}
-				resolvedCfg.Headers = append(resolvedCfg.Headers, restclient.Header{Name: cfg.Headers[i].Name, Value: fmt.Sprint(v)})
+				headers = append(headers, har.NameValuePair{Name: cfg.Headers[i].Name, Value: fmt.Sprint(v)})
 			} else {
-				resolvedCfg.Headers = append(resolvedCfg.Headers, restclient.Header{Name: cfg.Headers[i].Name, Value: cfg.Headers[i].Value})
+				headers = append(headers, har.NameValuePair{Name: cfg.Headers[i].Name, Value: cfg.Headers[i].Value})
 			}
 		}
 	}
 
-	client := restclient.NewClient(&resolvedCfg.Config, opts...)
+	request, err := lks.requestTemplate(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	execCfg := cfg.Config
+	for _, o := range opts {
+		o(&execCfg)
+	}
+
+	var client *restclient.Client
+	var timeout time.Duration
+	ownsClient := execCfg.TraceGroupName != ""
+	if ownsClient {
+		client = restclient.NewClient(&execCfg)
+	} else {
+		poolCfg := execCfg
+		if poolCfg.RestTimeout != cfg.RestTimeout {
+			timeout, poolCfg.RestTimeout = poolCfg.RestTimeout, cfg.RestTimeout
+		}
+		client = lks.clients.get(cfg.Id, poolCfg)
+	}
 
 	h := cfg.Host.FixValues()
 	log.Trace().Str("scheme", h.Scheme).Int("port", h.Port).Str("host-name", h.HostName).Msg(semLogContext)
-	return &Client{client: client, host: h, method: resolvedCfg.Method, path: resolvedCfg.Path, useResponse: resolvedCfg.Type == ActionTypeEnrich}, nil
+	return &Client{
+		client:      client,
+		ownsClient:  ownsClient,
+		timeout:     timeout,
+		host:        h,
+		method:      resolvedCfg.Method,
+		path:        resolvedCfg.Path,
+		headers:     headers,
+		span:        execCfg.Span,
+		harSpan:     execCfg.HarSpan,
+		useResponse: resolvedCfg.Type == ActionTypeEnrich,
+		mapping:     cfg.ResponseMapping,
+		success:     cfg.Success,
+		request:     request,
+		eCtx:        expressionCtx,
+	}, nil
 }
 
-func (c *Client) ExecuteAction(actionId string, actionBody map[string]interface{}) (map[string]interface{}, error) {
+// ExecuteAction sends the body to the action. The headers of the request context follow, and so override, the ones of the action
+// configuration; without a request id in the context the one set by the configuration is used or, lacking that, a new one.
+func (c *Client) ExecuteAction(reqCtx ApiRequestContext, actionId string, actionBody map[string]interface{}) (map[string]interface{}, error) {
 
 	const semLogContext = semLogContextBase + "::execute-action"
 
-	b, err := json.Marshal(actionBody)
+	headers := append([]har.NameValuePair{}, c.headers...)
+	if c.request.contentType != "" {
+		headers = append(headers, har.NameValuePair{Name: ContentTypeHeaderName, Value: c.request.contentType})
+	}
+	headers = append(headers, reqCtx.getHeaders()...)
+
+	reqId := reqCtx.RequestId
+	if reqId == "" {
+		if v, ok := findHeader(headers, RequestIdHeaderName); ok && v != "" {
+			reqId = v
+		} else {
+			reqId = util.NewObjectId().String()
+			headers = append(headers, har.NameValuePair{Name: RequestIdHeaderName, Value: reqId})
+		}
+	}
+
+	request, callerVars, err := c.request.withCallerReferences(c.eCtx)
+	if err != nil {
+		return nil, fmt.Errorf("action %s: %w", actionId, err)
+	}
+
+	tCtx, err := templateContext(actionId, reqId, reqCtx.LRAId, callerVars, actionBody)
+	if err != nil {
+		return nil, err
+	}
+
+	bCtx, err := request.bodyContext(tCtx, actionId, reqId, reqCtx.LRAId, callerVars, actionBody)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := c.client.NewRequest(c.method, c.Url(nil), b, nil, nil)
+	b, err := request.requestBody(bCtx, c.eCtx, actionId, actionBody)
 	if err != nil {
 		return nil, err
 	}
 
-	harEntry, err := c.client.Execute(req, restclient.ExecutionWithOpName("actions-client"), restclient.ExecutionWithRequestId("auto-req-id"))
+	qParams, err := request.resolveQueryParams(tCtx, actionId)
+	if err != nil {
+		return nil, err
+	}
+
+	span := reqCtx.Span
+	if span == nil {
+		span = c.span
+	}
+
+	harSpan := reqCtx.HarSpan
+	if harSpan == nil {
+		harSpan = c.harSpan
+	}
+
+	req, err := c.client.NewRequest(c.method, c.Url(qParams), b, headers, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	harEntry, err := c.execute(req,
+		restclient.ExecutionWithOpName("actions-client"),
+		restclient.ExecutionWithRequestId(reqId),
+		restclient.ExecutionWithLraId(reqCtx.LRAId),
+		restclient.ExecutionWithSpan(span),
+		restclient.ExecutionWithHarSpan(harSpan))
 	c.harEntries = append(c.harEntries, harEntry)
 	if err != nil {
 		return nil, &ActionResponse{
@@ -109,9 +203,41 @@ func (c *Client) ExecuteAction(actionId string, actionBody map[string]interface{
 
 	log.Info().Str("action-id", actionId).Int("status-code", harEntry.Response.Status).Msg(semLogContext)
 
+	if len(c.success) > 0 {
+		var resp map[string]interface{}
+		resp, err = responseBodyAsMap(harEntry)
+		if err == nil {
+			err = checkSuccess(c.success, resp)
+		}
+
+		if err != nil {
+			log.Warn().Err(err).Str("action-id", actionId).Msg(semLogContext + " success predicates not satisfied")
+			var ar *ActionResponse
+			if errors.As(err, &ar) {
+				return nil, ar
+			}
+
+			return nil, &ActionResponse{
+				StatusCode:  http.StatusInternalServerError,
+				Description: err.Error(),
+				Ts:          time.Now().Format(time.RFC3339Nano),
+			}
+		}
+	}
+
 	if c.useResponse {
 		var m map[string]interface{}
 		m, err = handleEnrichingResponse(harEntry)
+		if err == nil && len(c.mapping) > 0 {
+			m, err = mapResponse(c.mapping, m)
+			if err != nil {
+				return nil, &ActionResponse{
+					StatusCode:  http.StatusInternalServerError,
+					Description: err.Error(),
+					Ts:          time.Now().Format(time.RFC3339Nano),
+				}
+			}
+		}
 		return m, err
 		/*
 			if err == nil {
@@ -127,6 +253,40 @@ func (c *Client) ExecuteAction(actionId string, actionBody map[string]interface{
 	return nil, err
 }
 
+// execute sends the request within the timeout of the client, if any. On timeout the request is abandoned, bounded anyway by the
+// timeout of the rest client, and a gateway timeout entry is returned.
+func (c *Client) execute(req *har.Request, opts ...restclient.ExecutionContextOption) (*har.Entry, error) {
+	if c.timeout <= 0 {
+		return c.client.Execute(req, opts...)
+	}
+
+	type result struct {
+		entry *har.Entry
+		err   error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		e, err := c.client.Execute(req, opts...)
+		done <- result{entry: e, err: err}
+	}()
+
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+
+	select {
+	case r := <-done:
+		return r.entry, r.err
+	case <-timer.C:
+		err := fmt.Errorf("request timeout after %s", c.timeout)
+		return &har.Entry{
+			StartedDateTime: time.Now().Format(time.RFC3339Nano),
+			Request:         req,
+			Response:        har.NewResponse(http.StatusGatewayTimeout, http.StatusText(http.StatusGatewayTimeout), "text/plain", []byte(err.Error()), nil),
+		}, err
+	}
+}
+
 func handleEnrichingResponse(harEntry *har.Entry) (map[string]interface{}, error) {
 
 	const semLogContext = semLogContextBase + "::handle-enrich-response"
@@ -206,6 +366,10 @@ func handleBooleanResponse(harEntry *har.Entry) error {
 }
 
 func (lks *LinkedService) CallAction(actionId string, expressionCtx *expression.Context, body map[string]interface{}, opts ...restclient.Option) (map[string]interface{}, error) {
+	return lks.CallActionWithContext(ApiRequestContext{}, actionId, expressionCtx, body, opts...)
+}
+
+func (lks *LinkedService) CallActionWithContext(reqCtx ApiRequestContext, actionId string, expressionCtx *expression.Context, body map[string]interface{}, opts ...restclient.Option) (map[string]interface{}, error) {
 
 	const semLogContext = semLogContextBase + "::call-action"
 
@@ -231,7 +395,7 @@ func (lks *LinkedService) CallAction(actionId string, expressionCtx *expression.
 	//	actionBody[n] = v
 	//}
 
-	m, err := cli.ExecuteAction(actionId, body)
+	m, err := cli.ExecuteAction(reqCtx, actionId, body)
 	cli.Close()
 	if err != nil {
 		return nil, err
@@ -240,54 +404,6 @@ func (lks *LinkedService) CallAction(actionId string, expressionCtx *expression.
 	return m, nil
 }
 
-/*
-func (lks *LinkedService) CallActions(acts []token.Action, expressionCtx *expression.Context, body map[string]interface{}, opts ...restclient.Option) (map[string]interface{}, error) {
-
-		const semLogContext = semLogContextBase + "::call-actions"
-
-		// Initialize action-body
-		actionBody := make(map[string]interface{})
-		for n, v := range body {
-			actionBody[n] = v
-		}
-
-		for _, actId := range acts {
-
-			c, ok := lks.FindConfigByActionId(actId.ActionId)
-			if !ok {
-				log.Error().Str("action-id", actId.ActionId).Msg(semLogContext + " action not found")
-				return nil, &ActionResponse{
-					StatusCode: http.StatusInternalServerError,
-					Message:    fmt.Sprintf("action %s not found", actId),
-					Ts:         time.Now().Format(time.RFC3339Nano),
-				}
-			} else {
-				log.Trace().Str("action-id", actId.ActionId).Msg(semLogContext)
-			}
-
-			cli, err := lks.NewClient(c, expressionCtx, opts...)
-			if err != nil {
-				return nil, err
-			}
-
-			// add or override actionBody with action.properties
-			for n, v := range actId.Properties {
-				actionBody[n] = v
-			}
-
-			m, err := cli.ExecuteAction(actId.ActionId, actionBody)
-			cli.Close()
-			if err != nil {
-				return nil, err
-			}
-
-			actionBody = m
-		}
-
-		return actionBody, nil
-	}
-*/
-
 func (lks *LinkedService) CallActionsDeprecated(acts []string, expressionCtx *expression.Context, body map[string]interface{}, opts ...restclient.Option) (map[string]interface{}, error) {
 
 	const semLogContext = semLogContextBase + "::call-actions"
@@ -316,7 +432,7 @@ func (lks *LinkedService) CallActionsDeprecated(acts []string, expressionCtx *ex
 			return nil, err
 		}
 
-		m, err := cli.ExecuteAction(actId, actionBody)
+		m, err := cli.ExecuteAction(ApiRequestContext{}, actId, actionBody)
 		cli.Close()
 		if err != nil {
 			return nil, err
@@ -0,0 +1,306 @@
+package actionsclient
+
+import (
+	"fmt"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-common/util/expression"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-client/restclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+type MergeStrategy string
+
+const (
+	// MergeReplace the response of an enrich action replaces the body, as the deprecated chaining does.
+	MergeReplace MergeStrategy = "replace"
+	// MergeDeep the response is merged into the body recursively, objects are merged and any other value overwritten.
+	MergeDeep MergeStrategy = "deep-merge"
+	// MergeNamespaced the response is put in the body under the id of the action.
+	MergeNamespaced MergeStrategy = "namespaced"
+)
+
+type ErrorPolicy string
+
+const (
+	StopOnError     ErrorPolicy = "stop"
+	ContinueOnError ErrorPolicy = "continue"
+)
+
+type ActionOutcome string
+
+const (
+	ActionOutcomeOk          ActionOutcome = "ok"
+	ActionOutcomeSkipped     ActionOutcome = "skipped"
+	ActionOutcomeFailed      ActionOutcome = "failed"
+	ActionOutcomeNotExecuted ActionOutcome = "not-executed"
+)
+
+// ActionCaller executes an action given its id, implemented by LinkedService.
+type ActionCaller interface {
+	CallAction(actionId string, expressionCtx *expression.Context, body map[string]interface{}, opts ...restclient.Option) (map[string]interface{}, error)
+}
+
+// ContextActionCaller executes an action within a request context, implemented by LinkedService.
+type ContextActionCaller interface {
+	CallActionWithContext(reqCtx ApiRequestContext, actionId string, expressionCtx *expression.Context, body map[string]interface{}, opts ...restclient.Option) (map[string]interface{}, error)
+}
+
+type boundActionCaller struct {
+	caller ContextActionCaller
+	reqCtx ApiRequestContext
+}
+
+func (b boundActionCaller) CallAction(actionId string, expressionCtx *expression.Context, body map[string]interface{}, opts ...restclient.Option) (map[string]interface{}, error) {
+	return b.caller.CallActionWithContext(b.reqCtx, actionId, expressionCtx, body, opts...)
+}
+
+// BindRequestContext an ActionCaller whose calls carry the request context, i.e. to run a pipeline or a fan-out within a request.
+func BindRequestContext(caller ContextActionCaller, reqCtx ApiRequestContext) ActionCaller {
+	return boundActionCaller{caller: caller, reqCtx: reqCtx}
+}
+
+// ActionOptions per action settings of a pipeline. Condition is a boolean expression evaluated against the body built so far
+// (i.e. "{$.score} > 10"): the action is skipped when false. Empty values take the pipeline defaults.
+type ActionOptions struct {
+	Condition string        `yaml:"condition,omitempty" mapstructure:"condition,omitempty" json:"condition,omitempty"`
+	Merge     MergeStrategy `yaml:"merge,omitempty" mapstructure:"merge,omitempty" json:"merge,omitempty"`
+	OnError   ErrorPolicy   `yaml:"on-error,omitempty" mapstructure:"on-error,omitempty" json:"on-error,omitempty"`
+}
+
+type PipelineOptions struct {
+	Merge   MergeStrategy
+	OnError ErrorPolicy
+	Vars    map[string]interface{}
+	Actions map[string]ActionOptions
+}
+
+type PipelineOption func(opts *PipelineOptions)
+
+func PipelineWithMergeStrategy(s MergeStrategy) PipelineOption {
+	return func(opts *PipelineOptions) {
+		opts.Merge = s
+	}
+}
+
+func PipelineWithErrorPolicy(p ErrorPolicy) PipelineOption {
+	return func(opts *PipelineOptions) {
+		opts.OnError = p
+	}
+}
+
+// PipelineWithVars variables available to the conditions as {v:name}.
+func PipelineWithVars(vars map[string]interface{}) PipelineOption {
+	return func(opts *PipelineOptions) {
+		opts.Vars = vars
+	}
+}
+
+func PipelineWithActionOptions(actionId string, ao ActionOptions) PipelineOption {
+	return func(opts *PipelineOptions) {
+		if opts.Actions == nil {
+			opts.Actions = make(map[string]ActionOptions)
+		}
+		opts.Actions[actionId] = ao
+	}
+}
+
+type ActionReport struct {
+	ActionId  string                 `yaml:"action-id,omitempty" mapstructure:"action-id,omitempty" json:"action-id,omitempty"`
+	Outcome   ActionOutcome          `yaml:"outcome,omitempty" mapstructure:"outcome,omitempty" json:"outcome,omitempty"`
+	Condition string                 `yaml:"condition,omitempty" mapstructure:"condition,omitempty" json:"condition,omitempty"`
+	Merge     MergeStrategy          `yaml:"merge,omitempty" mapstructure:"merge,omitempty" json:"merge,omitempty"`
+	Duration  time.Duration          `yaml:"duration,omitempty" mapstructure:"duration,omitempty" json:"duration,omitempty"`
+	Response  map[string]interface{} `yaml:"response,omitempty" mapstructure:"response,omitempty" json:"response,omitempty"`
+	Error     string                 `yaml:"error,omitempty" mapstructure:"error,omitempty" json:"error,omitempty"`
+	Err       error                  `yaml:"-" mapstructure:"-" json:"-"`
+}
+
+// PipelineReport the body resulting from the execution and the report of each action, in the order of the pipeline.
+type PipelineReport struct {
+	Body    map[string]interface{} `yaml:"body,omitempty" mapstructure:"body,omitempty" json:"body,omitempty"`
+	Actions []ActionReport         `yaml:"actions,omitempty" mapstructure:"actions,omitempty" json:"actions,omitempty"`
+}
+
+// Err the error of the first failed action.
+func (r *PipelineReport) Err() error {
+	for _, a := range r.Actions {
+		if a.Outcome == ActionOutcomeFailed {
+			return a.Err
+		}
+	}
+
+	return nil
+}
+
+func (r *PipelineReport) NumberOf(o ActionOutcome) int {
+	n := 0
+	for _, a := range r.Actions {
+		if a.Outcome == o {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Pipeline executes a list of actions in sequence: each action receives the body built so far plus its own properties and, if
+// enrich, contributes its response according to the merge strategy.
+type Pipeline struct {
+	caller ActionCaller
+	opts   PipelineOptions
+}
+
+func NewPipeline(caller ActionCaller, opts ...PipelineOption) *Pipeline {
+	pOpts := PipelineOptions{Merge: MergeDeep, OnError: StopOnError}
+	for _, o := range opts {
+		o(&pOpts)
+	}
+
+	return &Pipeline{caller: caller, opts: pOpts}
+}
+
+func (lks *LinkedService) NewPipeline(opts ...PipelineOption) *Pipeline {
+	return NewPipeline(lks, opts...)
+}
+
+// Execute runs the actions. With the stop policy the first failure ends the execution, the remaining actions are reported as not
+// executed and the error is returned along with the report; with the continue policy failures are only reported.
+func (p *Pipeline) Execute(acts []token.Action, expressionCtx *expression.Context, body map[string]interface{}, opts ...restclient.Option) (*PipelineReport, error) {
+	const semLogContext = semLogContextBase + "::pipeline-execute"
+
+	report := &PipelineReport{Body: copyMap(body)}
+	var stopErr error
+	for _, act := range acts {
+		ao := p.actionOptions(act.ActionId)
+		ar := ActionReport{ActionId: act.ActionId, Condition: ao.Condition, Merge: ao.Merge, Outcome: ActionOutcomeNotExecuted}
+		if stopErr != nil {
+			report.Actions = append(report.Actions, ar)
+			continue
+		}
+
+		start := time.Now()
+		ok, err := p.evalCondition(ao.Condition, report.Body)
+		if err == nil && !ok {
+			log.Trace().Str("action-id", act.ActionId).Str("condition", ao.Condition).Msg(semLogContext + " action skipped")
+			ar.Outcome = ActionOutcomeSkipped
+			report.Actions = append(report.Actions, ar)
+			continue
+		}
+
+		var resp map[string]interface{}
+		if err == nil {
+			resp, err = p.caller.CallAction(act.ActionId, expressionCtx, actionBody(report.Body, act.Properties), opts...)
+		}
+		ar.Duration = time.Since(start)
+
+		if err != nil {
+			log.Error().Err(err).Str("action-id", act.ActionId).Msg(semLogContext)
+			ar.Outcome, ar.Err, ar.Error = ActionOutcomeFailed, err, err.Error()
+			report.Actions = append(report.Actions, ar)
+			if ao.OnError == StopOnError {
+				stopErr = err
+			}
+			continue
+		}
+
+		ar.Outcome, ar.Response = ActionOutcomeOk, resp
+		report.Body = MergeResponse(ao.Merge, act.ActionId, report.Body, resp)
+		report.Actions = append(report.Actions, ar)
+	}
+
+	return report, stopErr
+}
+
+func (p *Pipeline) actionOptions(actionId string) ActionOptions {
+	ao := p.opts.Actions[actionId]
+	if ao.Merge == "" {
+		ao.Merge = p.opts.Merge
+	}
+
+	if ao.OnError == "" {
+		ao.OnError = p.opts.OnError
+	}
+
+	return ao
+}
+
+func (p *Pipeline) evalCondition(cond string, body map[string]interface{}) (bool, error) {
+	if cond == "" {
+		return true, nil
+	}
+
+	eCtx, err := expression.NewContext(expression.WithVars(p.opts.Vars), expression.WithMapInput(body))
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := eCtx.BoolEvalOne(cond)
+	if err != nil {
+		return false, fmt.Errorf("condition %q: %w", cond, err)
+	}
+
+	return ok, nil
+}
+
+// CallActions chains the actions replacing the body with the response of each enrich action, the properties of each action
+// are added to its body.
+func (lks *LinkedService) CallActions(acts []token.Action, expressionCtx *expression.Context, body map[string]interface{}, opts ...restclient.Option) (map[string]interface{}, error) {
+	report, err := lks.NewPipeline(PipelineWithMergeStrategy(MergeReplace)).Execute(acts, expressionCtx, body, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return report.Body, nil
+}
+
+// MergeResponse merges the response of an action into body according to the strategy. Bool actions have no response and leave the body as is.
+func MergeResponse(s MergeStrategy, actionId string, body map[string]interface{}, resp map[string]interface{}) map[string]interface{} {
+	if resp == nil {
+		return body
+	}
+
+	switch s {
+	case MergeReplace:
+		return copyMap(resp)
+	case MergeNamespaced:
+		m := copyMap(body)
+		m[actionId] = copyMap(resp)
+		return m
+	}
+
+	return deepMerge(copyMap(body), resp)
+}
+
+func deepMerge(dst map[string]interface{}, src map[string]interface{}) map[string]interface{} {
+	for n, v := range src {
+		sm, sok := v.(map[string]interface{})
+		dm, dok := dst[n].(map[string]interface{})
+		if sok && dok {
+			dst[n] = deepMerge(copyMap(dm), sm)
+			continue
+		}
+
+		dst[n] = v
+	}
+
+	return dst
+}
+
+func actionBody(body map[string]interface{}, props map[string]interface{}) map[string]interface{} {
+	m := copyMap(body)
+	for n, v := range props {
+		m[n] = v
+	}
+
+	return m
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(m))
+	for n, v := range m {
+		c[n] = v
+	}
+
+	return c
+}
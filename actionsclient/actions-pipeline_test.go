@@ -0,0 +1,81 @@
+package actionsclient_test
+
+import (
+	"errors"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-common/util/expression"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-client/restclient"
+	actions "github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/actionsclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type callerFunc func(actionId string, body map[string]interface{}) (map[string]interface{}, error)
+
+func (f callerFunc) CallAction(actionId string, _ *expression.Context, body map[string]interface{}, _ ...restclient.Option) (map[string]interface{}, error) {
+	return f(actionId, body)
+}
+
+func TestPipeline(t *testing.T) {
+
+	var calls []string
+	var bodies []map[string]interface{}
+	caller := callerFunc(func(actionId string, body map[string]interface{}) (map[string]interface{}, error) {
+		calls = append(calls, actionId)
+		bodies = append(bodies, body)
+		switch actionId {
+		case "guard":
+			return nil, nil
+		case "score":
+			return map[string]interface{}{"score": 20, "customer": map[string]interface{}{"segment": "gold"}}, nil
+		case "bonus":
+			return map[string]interface{}{"bonus": 5}, nil
+		case "fail":
+			return nil, errors.New("action failed")
+		}
+		return map[string]interface{}{"action": actionId}, nil
+	})
+
+	acts := []token.Action{
+		{ActionId: "guard"},
+		{ActionId: "score", Properties: map[string]interface{}{"campaign": "c1"}},
+		{ActionId: "bonus"},
+		{ActionId: "low-score"},
+		{ActionId: "audit"},
+	}
+
+	input := map[string]interface{}{"customer": map[string]interface{}{"name": "mario"}}
+	p := actions.NewPipeline(caller,
+		actions.PipelineWithActionOptions("bonus", actions.ActionOptions{Condition: "{$.score} > 10"}),
+		actions.PipelineWithActionOptions("low-score", actions.ActionOptions{Condition: "{$.score} <= 10"}),
+		actions.PipelineWithActionOptions("audit", actions.ActionOptions{Merge: actions.MergeNamespaced}),
+	)
+
+	report, err := p.Execute(acts, nil, input)
+	require.NoError(t, err)
+	require.NoError(t, report.Err())
+	require.Equal(t, []string{"guard", "score", "bonus", "audit"}, calls)
+	require.Equal(t, "c1", bodies[1]["campaign"])
+	require.NotContains(t, input, "score")
+
+	require.Equal(t, map[string]interface{}{"name": "mario", "segment": "gold"}, report.Body["customer"])
+	require.Equal(t, 5, report.Body["bonus"])
+	require.Equal(t, map[string]interface{}{"action": "audit"}, report.Body["audit"])
+	require.Equal(t, 4, report.NumberOf(actions.ActionOutcomeOk))
+	require.Equal(t, actions.ActionOutcomeSkipped, report.Actions[3].Outcome)
+
+	calls = nil
+	acts = []token.Action{{ActionId: "fail"}, {ActionId: "audit"}}
+	report, err = actions.NewPipeline(caller).Execute(acts, nil, input)
+	require.Error(t, err)
+	require.Equal(t, []string{"fail"}, calls)
+	require.Equal(t, actions.ActionOutcomeFailed, report.Actions[0].Outcome)
+	require.Equal(t, actions.ActionOutcomeNotExecuted, report.Actions[1].Outcome)
+
+	calls = nil
+	report, err = actions.NewPipeline(caller, actions.PipelineWithErrorPolicy(actions.ContinueOnError), actions.PipelineWithMergeStrategy(actions.MergeReplace)).Execute(acts, nil, input)
+	require.NoError(t, err)
+	require.Error(t, report.Err())
+	require.Equal(t, []string{"fail", "audit"}, calls)
+	require.Equal(t, map[string]interface{}{"action": "audit"}, report.Body)
+}
@@ -4,24 +4,72 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-common/util/expression"
 	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-archive/har"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-archive/hartracing"
 	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-client/restclient"
+	"github.com/opentracing/opentracing-go"
 	"github.com/rs/zerolog/log"
 	"net/url"
 	"strings"
+	"time"
 )
 
 const semLogContextBase = "actions-client"
 
 type LinkedService struct {
-	cfg []Config
+	cfg      []Config
+	requests map[string]requestTemplate
+	clients  clientPool
 }
 
 func NewInstanceWithConfig(cfg []Config) (*LinkedService, error) {
-	lks := &LinkedService{cfg: cfg}
+	lks := &LinkedService{cfg: cfg, requests: make(map[string]requestTemplate)}
+	for i, c := range cfg {
+		if err := validateResponseHandling(&cfg[i]); err != nil {
+			return nil, err
+		}
+
+		rt, err := validateRequestTemplate(&cfg[i])
+		if err != nil {
+			return nil, err
+		}
+		lks.requests[c.Id] = rt
+
+		if c.Compensation == "" {
+			continue
+		}
+
+		if _, ok := lks.FindConfigByActionId(c.Compensation); !ok || c.Compensation == c.Id {
+			return nil, fmt.Errorf("action %s: invalid compensation %s", c.Id, c.Compensation)
+		}
+	}
+
 	return lks, nil
 }
 
+// requestTemplate the request template of the action, parsed when the linked service has been created. A configuration unknown to
+// the linked service, or with a different body, gets its body template parsed.
+func (lks *LinkedService) requestTemplate(c *Config) (requestTemplate, error) {
+	rt := newRequestTemplate(c)
+	if parsed, ok := lks.requests[c.Id]; ok && parsed.body == rt.body && parsed.bodyTemplate == rt.bodyTemplate {
+		rt.goTemplate = parsed.goTemplate
+		return rt, nil
+	}
+
+	return rt, rt.parse(c.Id)
+}
+
+// Close releases the pooled rest clients.
+func (lks *LinkedService) Close() {
+	lks.clients.close()
+}
+
+// PoolSize the number of pooled rest clients.
+func (lks *LinkedService) PoolSize() int {
+	return lks.clients.size()
+}
+
 func (lks *LinkedService) FindConfigByActionId(actId string) (Config, bool) {
 	for _, c := range lks.cfg {
 		if c.Id == actId {
@@ -32,6 +80,16 @@ func (lks *LinkedService) FindConfigByActionId(actId string) (Config, bool) {
 	return Config{}, false
 }
 
+// CompensationOf the id of the compensating action declared by the action, if any.
+func (lks *LinkedService) CompensationOf(actId string) (string, bool) {
+	c, ok := lks.FindConfigByActionId(actId)
+	if !ok || c.Compensation == "" {
+		return "", false
+	}
+
+	return c.Compensation, true
+}
+
 type HostInfo struct {
 	Scheme   string `mapstructure:"scheme,omitempty" json:"scheme,omitempty" yaml:"scheme,omitempty"`
 	HostName string `mapstructure:"name,omitempty" json:"name,omitempty" yaml:"name,omitempty"`
@@ -82,19 +140,46 @@ type Config struct {
 	Host              HostInfo   `mapstructure:"host,omitempty" json:"host,omitempty" yaml:"host,omitempty"`
 	Method            string     `mapstructure:"method,omitempty" json:"method,omitempty" yaml:"method,omitempty"`
 	Path              string     `mapstructure:"path,omitempty" json:"path,omitempty" yaml:"path,omitempty"`
+	// Compensation the id of the action that undoes this one when a saga fails.
+	Compensation string `mapstructure:"compensation,omitempty" json:"compensation,omitempty" yaml:"compensation,omitempty"`
+	// ResponseMapping the variables extracted from the response of an enrich action, the whole response if empty.
+	ResponseMapping []ResponseMapping `mapstructure:"response-mapping,omitempty" json:"response-mapping,omitempty" yaml:"response-mapping,omitempty"`
+	// Success the predicates a 200 response has to satisfy for the action to succeed.
+	Success []SuccessPredicate `mapstructure:"success,omitempty" json:"success,omitempty" yaml:"success,omitempty"`
+	// ContentType of the request: json (default), form-urlencoded or xml, the latter requires a body template.
+	ContentType string `mapstructure:"content-type,omitempty" json:"content-type,omitempty" yaml:"content-type,omitempty"`
+	// Body the template of the request body, the action body is sent if empty. BodyTemplate tells how to resolve it: expression
+	// (default), with the action body as input and the headers and vars of the expression context of the call, the values xml escaped
+	// for xml content types, or go.
+	Body         string            `mapstructure:"body,omitempty" json:"body,omitempty" yaml:"body,omitempty"`
+	BodyTemplate string            `mapstructure:"body-template,omitempty" json:"body-template,omitempty" yaml:"body-template,omitempty"`
+	FormParams   []ParamDefinition `mapstructure:"form-params,omitempty" json:"form-params,omitempty" yaml:"form-params,omitempty"`
+	QueryParams  []ParamDefinition `mapstructure:"query-params,omitempty" json:"query-params,omitempty" yaml:"query-params,omitempty"`
 }
 
 type Client struct {
 	method      string
 	path        string
 	host        HostInfo
+	headers     []har.NameValuePair
 	client      *restclient.Client
+	ownsClient  bool
+	timeout     time.Duration
+	span        opentracing.Span
+	harSpan     hartracing.Span
 	harEntries  []*har.Entry
 	useResponse bool
+	mapping     []ResponseMapping
+	success     []SuccessPredicate
+	request     requestTemplate
+	eCtx        *expression.Context
 }
 
+// Close releases the rest client if owned, that is if created with a trace group name. Pooled clients are released by LinkedService.Close.
 func (c *Client) Close() {
-	c.client.Close()
+	if c.ownsClient {
+		c.client.Close()
+	}
 }
 
 func (c *Client) Url(qParams []har.NameValuePair) string {
@@ -107,7 +192,11 @@ func (c *Client) Url(qParams []har.NameValuePair) string {
 	sb.WriteString(c.path)
 
 	if len(qParams) > 0 {
-		sb.WriteString("?")
+		if strings.Contains(c.path, "?") {
+			sb.WriteString("&")
+		} else {
+			sb.WriteString("?")
+		}
 		for i, qp := range qParams {
 			if i > 0 {
 				sb.WriteString("&")
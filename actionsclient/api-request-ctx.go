@@ -0,0 +1,105 @@
+package actionsclient
+
+import (
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-common/util"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-archive/har"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-archive/hartracing"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-client/restclient"
+	"github.com/opentracing/opentracing-go"
+	"github.com/rs/zerolog/log"
+	"strings"
+)
+
+const (
+	RequestIdHeaderName      = "Request-id"
+	LraHttpContextHeaderName = "Long-Running-Action"
+)
+
+type ApiRequestContext struct {
+	RequestId string              `yaml:"request-id,omitempty" mapstructure:"request-id,omitempty" json:"request-id,omitempty"`
+	LRAId     string              `yaml:"lra-id,omitempty" mapstructure:"lra-id,omitempty" json:"lra-id,omitempty"`
+	Headers   []restclient.Header `mapstructure:"headers,omitempty" json:"headers,omitempty" yaml:"headers,omitempty"`
+	Span      opentracing.Span    `yaml:"-" mapstructure:"-" json:"-"`
+	HarSpan   hartracing.Span     `yaml:"-" mapstructure:"-" json:"-"`
+}
+
+type APIRequestContextOption func(*ApiRequestContext)
+
+func ApiRequestWithAutoRequestId() APIRequestContextOption {
+	return func(ctx *ApiRequestContext) {
+		ctx.RequestId = util.NewObjectId().String()
+	}
+}
+
+func ApiRequestWithRequestId(reqId string) APIRequestContextOption {
+	const semLogContext = semLogContextBase + "::request-req-id"
+	return func(ctx *ApiRequestContext) {
+		if reqId == "" {
+			reqId = util.NewObjectId().String()
+			log.Info().Msg(semLogContext + " reqId set to empty string.... auto generated")
+		}
+		ctx.RequestId = reqId
+	}
+}
+
+func ApiRequestWithLraId(lraId string) APIRequestContextOption {
+	return func(ctx *ApiRequestContext) {
+		ctx.LRAId = lraId
+	}
+}
+
+func ApiRequestWithSpan(span opentracing.Span) APIRequestContextOption {
+	return func(ctx *ApiRequestContext) {
+		ctx.Span = span
+	}
+}
+
+func ApiRequestWithHarSpan(span hartracing.Span) APIRequestContextOption {
+	return func(ctx *ApiRequestContext) {
+		ctx.HarSpan = span
+	}
+}
+
+func ApiRequestWithHeader(n, v string) APIRequestContextOption {
+	return func(ctx *ApiRequestContext) {
+		ctx.Headers = append(ctx.Headers, restclient.Header{Name: n, Value: v})
+	}
+}
+
+// getHeaders the headers of the request context. Unlike the other clients the request id is not generated when missing: the
+// action configuration may set it from the incoming request headers.
+func (arc *ApiRequestContext) getHeaders() []har.NameValuePair {
+	var nvp []har.NameValuePair
+
+	if arc.RequestId != "" {
+		nvp = append(nvp, har.NameValuePair{Name: RequestIdHeaderName, Value: arc.RequestId})
+	}
+
+	if arc.LRAId != "" {
+		nvp = append(nvp, har.NameValuePair{Name: LraHttpContextHeaderName, Value: arc.LRAId})
+	}
+
+	for _, h := range arc.Headers {
+		nvp = append(nvp, har.NameValuePair{Name: h.Name, Value: h.Value})
+	}
+	return nvp
+}
+
+func NewApiRequestContext(opts ...APIRequestContextOption) ApiRequestContext {
+	ar := ApiRequestContext{}
+	for _, o := range opts {
+		o(&ar)
+	}
+
+	return ar
+}
+
+func findHeader(headers []har.NameValuePair, n string) (string, bool) {
+	for _, h := range headers {
+		if strings.EqualFold(h.Name, n) {
+			return h.Value, true
+		}
+	}
+
+	return "", false
+}
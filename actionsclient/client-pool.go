@@ -0,0 +1,83 @@
+package actionsclient
+
+import (
+	"fmt"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-client/restclient"
+	"github.com/rs/zerolog/log"
+	"sync"
+	"time"
+)
+
+// clientPool the rest clients of the actions shared by the calls: a rest client owns its http transport so creating one per call
+// prevents connections reuse. Clients are keyed by action id and by the settings shaping connections and retries; the headers and the
+// spans are per request and the timeout of a call, if different from the configured one, is enforced per request by the Client.
+type clientPool struct {
+	mu      sync.Mutex
+	clients map[clientPoolKey]*restclient.Client
+}
+
+type clientPoolKey struct {
+	actionId          string
+	restTimeout       time.Duration
+	skipVerify        bool
+	retryCount        int
+	retryWaitTime     time.Duration
+	retryMaxWaitTime  time.Duration
+	retryOnHttpError  string
+	harTracingEnabled bool
+	traceRequestName  string
+}
+
+func newClientPoolKey(actionId string, cfg *restclient.Config) clientPoolKey {
+	return clientPoolKey{
+		actionId:          actionId,
+		restTimeout:       cfg.RestTimeout,
+		skipVerify:        cfg.SkipVerify,
+		retryCount:        cfg.RetryCount,
+		retryWaitTime:     cfg.RetryWaitTime,
+		retryMaxWaitTime:  cfg.RetryMaxWaitTime,
+		retryOnHttpError:  fmt.Sprint(cfg.RetryOnHttpError),
+		harTracingEnabled: cfg.HarTracingEnabled,
+		traceRequestName:  cfg.TraceRequestName,
+	}
+}
+
+// get the pooled client of the action. The configuration must not carry a trace group name: such clients own a span and are not pooled.
+func (p *clientPool) get(actionId string, cfg restclient.Config) *restclient.Client {
+	const semLogContext = semLogContextBase + "::pool-get"
+
+	cfg.Headers, cfg.Span, cfg.HarSpan = nil, nil, nil
+	key := newClientPoolKey(actionId, &cfg)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cli, ok := p.clients[key]; ok {
+		return cli
+	}
+
+	if p.clients == nil {
+		p.clients = make(map[clientPoolKey]*restclient.Client)
+	}
+
+	log.Trace().Str("action-id", actionId).Int("pool-size", len(p.clients)+1).Msg(semLogContext + " new rest client")
+	cli := restclient.NewClient(&cfg)
+	p.clients[key] = cli
+	return cli
+}
+
+func (p *clientPool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.clients)
+}
+
+func (p *clientPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, cli := range p.clients {
+		cli.Close()
+	}
+	p.clients = nil
+}
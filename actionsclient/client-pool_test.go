@@ -0,0 +1,122 @@
+package actionsclient_test
+
+import (
+	"encoding/json"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-client/restclient"
+	actions "github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/actionsclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newActionsTestServer(t *testing.T) (*httptest.Server, actions.HostInfo, chan http.Header) {
+	headers := make(chan http.Header, 100)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Clone()
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"path": r.URL.Path})
+	}))
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+
+	return srv, actions.HostInfo{Scheme: u.Scheme, HostName: u.Hostname(), Port: port}, headers
+}
+
+func TestClientPool(t *testing.T) {
+
+	srv, host, headers := newActionsTestServer(t)
+	defer srv.Close()
+
+	lks, err := actions.NewInstanceWithConfig([]actions.Config{
+		{
+			Config: restclient.Config{Headers: []restclient.Header{{Name: "Request-id", Value: "cfg-req-id"}, {Name: "X-Channel", Value: "web"}}},
+			Id:     "enrich", Type: actions.ActionTypeEnrich, Host: host, Method: http.MethodPost, Path: "/enrich",
+		},
+		{Id: "check", Type: actions.ActionTypeBool, Host: host, Method: http.MethodPost, Path: "/check"},
+		{Id: "slow", Type: actions.ActionTypeEnrich, Host: host, Method: http.MethodPost, Path: "/slow"},
+	})
+	require.NoError(t, err)
+	defer lks.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := lks.CallAction("enrich", nil, map[string]interface{}{})
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, 1, lks.PoolSize())
+	for i := 0; i < 20; i++ {
+		h := <-headers
+		require.Equal(t, "cfg-req-id", h.Get(actions.RequestIdHeaderName))
+	}
+
+	// the request context overrides the configuration headers.
+	reqCtx := actions.NewApiRequestContext(actions.ApiRequestWithRequestId("req-1"), actions.ApiRequestWithLraId("lra-1"), actions.ApiRequestWithHeader("X-Channel", "app"))
+	m, err := lks.CallActionWithContext(reqCtx, "enrich", nil, map[string]interface{}{})
+	require.NoError(t, err)
+	require.Equal(t, "/enrich", m["path"])
+	h := <-headers
+	require.Equal(t, "req-1", h.Get(actions.RequestIdHeaderName))
+	require.Equal(t, "lra-1", h.Get(actions.LraHttpContextHeaderName))
+	require.Equal(t, "app", h.Get("X-Channel"))
+
+	// no request id anywhere: one is generated.
+	_, err = lks.CallAction("check", nil, map[string]interface{}{})
+	require.NoError(t, err)
+	h = <-headers
+	require.NotEmpty(t, h.Get(actions.RequestIdHeaderName))
+	require.Empty(t, h.Get(actions.LraHttpContextHeaderName))
+	require.Equal(t, 2, lks.PoolSize())
+
+	// the timeout of the call is per request and does not change the client, connection settings do.
+	_, err = lks.CallAction("check", nil, map[string]interface{}{}, restclient.WithTimeout(time.Second))
+	require.NoError(t, err)
+	<-headers
+	require.Equal(t, 2, lks.PoolSize())
+
+	_, err = lks.CallAction("check", nil, map[string]interface{}{}, restclient.WithSkipVerify(true))
+	require.NoError(t, err)
+	<-headers
+	require.Equal(t, 3, lks.PoolSize())
+
+	_, err = lks.CallAction("slow", nil, map[string]interface{}{}, restclient.WithTimeout(50*time.Millisecond))
+	var ar *actions.ActionResponse
+	require.ErrorAs(t, err, &ar)
+	require.Equal(t, http.StatusGatewayTimeout, ar.StatusCode)
+	<-headers
+	require.Equal(t, 4, lks.PoolSize())
+
+	// a trace group name gets a dedicated client.
+	_, err = lks.CallAction("check", nil, map[string]interface{}{}, restclient.WithTraceGroupName("group"))
+	require.NoError(t, err)
+	<-headers
+	require.Equal(t, 4, lks.PoolSize())
+
+	// the saga propagates its lra id to the actions.
+	st := &sagaTokens{}
+	report, err := lks.NewSaga(st).Execute(tokensclient.NewApiRequestContext(tokensclient.ApiRequestWithLraId("lra-saga")), actions.SagaDefinition{CtxId: "ctx", TokId: "tok", Actions: []token.Action{{ActionId: "check"}}}, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, actions.SagaOutcomeCommitted, report.Outcome)
+	h = <-headers
+	require.Equal(t, "lra-saga", h.Get(actions.LraHttpContextHeaderName))
+
+	lks.Close()
+	require.Equal(t, 0, lks.PoolSize())
+}
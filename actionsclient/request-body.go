@@ -0,0 +1,363 @@
+package actionsclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-common/util/expression"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-archive/har"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const (
+	BodyTemplateExpression = "expression"
+	BodyTemplateGo         = "go"
+
+	ContentTypeApplicationJson = "application/json"
+	ContentTypeFormUrlEncoded  = "application/x-www-form-urlencoded"
+	ContentTypeApplicationXml  = "application/xml"
+	ContentTypeTextXml         = "text/xml"
+	ContentTypeSoapXml         = "application/soap+xml"
+
+	ContentTypeHeaderName = "Content-Type"
+
+	TemplateVarActionId  = "action-id"
+	TemplateVarRequestId = "request-id"
+	TemplateVarLraId     = "lra-id"
+
+	// interpolateOnlyPrefix keeps the expression context from evaluating the resolved template as an expression when it contains
+	// operators, as xml bodies and free text values do.
+	interpolateOnlyPrefix = "!e:"
+)
+
+// ParamDefinition a query or form parameter of an action. The value is a template resolved as the body.
+type ParamDefinition struct {
+	Name  string `mapstructure:"name" json:"name" yaml:"name"`
+	Value string `mapstructure:"value,omitempty" json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// requestTemplate the parts of the action configuration that shape the request.
+type requestTemplate struct {
+	contentType  string
+	body         string
+	bodyTemplate string
+	formParams   []ParamDefinition
+	queryParams  []ParamDefinition
+	goTemplate   *template.Template
+}
+
+func newRequestTemplate(c *Config) requestTemplate {
+	rt := requestTemplate{
+		contentType:  strings.ToLower(c.ContentType),
+		body:         c.Body,
+		bodyTemplate: c.BodyTemplate,
+		formParams:   c.FormParams,
+		queryParams:  c.QueryParams,
+	}
+
+	if rt.bodyTemplate == "" {
+		rt.bodyTemplate = BodyTemplateExpression
+	}
+
+	return rt
+}
+
+// parse parses the go template of the body, if any. The functions are bound to the expression context of the call on execution.
+func (rt *requestTemplate) parse(actionId string) error {
+	if rt.bodyTemplate != BodyTemplateGo {
+		return nil
+	}
+
+	tmpl, err := template.New(actionId).Funcs(templateFuncs(nil)).Option("missingkey=zero").Parse(rt.body)
+	if err != nil {
+		return fmt.Errorf("action %s: body template: %w", actionId, err)
+	}
+
+	rt.goTemplate = tmpl
+	return nil
+}
+
+func isXmlContentType(ct string) bool {
+	return ct == ContentTypeApplicationXml || ct == ContentTypeTextXml || ct == ContentTypeSoapXml
+}
+
+// validateRequestTemplate validates the configuration and returns the request template of the action with the body template parsed.
+func validateRequestTemplate(c *Config) (requestTemplate, error) {
+	rt := newRequestTemplate(c)
+	switch rt.contentType {
+	case "", ContentTypeApplicationJson, ContentTypeFormUrlEncoded, ContentTypeApplicationXml, ContentTypeTextXml, ContentTypeSoapXml:
+	default:
+		return rt, fmt.Errorf("action %s: unsupported content-type %s", c.Id, c.ContentType)
+	}
+
+	if isXmlContentType(rt.contentType) && rt.body == "" {
+		return rt, fmt.Errorf("action %s: content-type %s requires a body template", c.Id, c.ContentType)
+	}
+
+	if len(rt.formParams) > 0 && rt.contentType != ContentTypeFormUrlEncoded {
+		return rt, fmt.Errorf("action %s: form params require content-type %s", c.Id, ContentTypeFormUrlEncoded)
+	}
+
+	for _, p := range append(append([]ParamDefinition{}, rt.formParams...), rt.queryParams...) {
+		if p.Name == "" {
+			return rt, fmt.Errorf("action %s: param with empty name", c.Id)
+		}
+	}
+
+	switch rt.bodyTemplate {
+	case BodyTemplateExpression:
+	case BodyTemplateGo:
+		if err := rt.parse(c.Id); err != nil {
+			return rt, err
+		}
+	default:
+		return rt, fmt.Errorf("action %s: unsupported body template %s", c.Id, rt.bodyTemplate)
+	}
+
+	return rt, nil
+}
+
+// templateFuncs the functions of go templates: json and xml escape their argument, eval resolves a template against the expression
+// context of the call (i.e. {{ eval "{h:request-id}" }}).
+func templateFuncs(expressionCtx *expression.Context) template.FuncMap {
+	return template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"xml": func(v interface{}) (string, error) {
+			var sb strings.Builder
+			err := xml.EscapeText(&sb, []byte(fmt.Sprint(v)))
+			return sb.String(), err
+		},
+		"eval": func(s string) (interface{}, error) {
+			if expressionCtx == nil {
+				return nil, errors.New("eval: no expression context")
+			}
+			return expressionCtx.EvalOne(s)
+		},
+	}
+}
+
+// templateContext the context of expression templates: the action body (properties included) as input, i.e. {$.cf} or {!$.cf}
+// to json escape, and the ids of the call as variables ({v:request-id}, {v:lra-id}, {v:action-id}). The caller vars are the values
+// of the references to the expression context of the call (see withCallerReferences).
+func templateContext(actionId string, reqId string, lraId string, callerVars map[string]interface{}, actionBody map[string]interface{}) (*expression.Context, error) {
+	vars := map[string]interface{}{TemplateVarActionId: actionId, TemplateVarRequestId: reqId, TemplateVarLraId: lraId}
+	return expression.NewContext(expression.WithVars(callerVars), expression.WithVars(vars), expression.WithMapInput(actionBody))
+}
+
+var callerReferenceRegexp = regexp.MustCompile(`\{(!?)([hv]:[^{}]+)\}`)
+
+// withCallerReferences a copy of the request template with the header and variable references of the expression templates, i.e.
+// {h:x-tenant} or {!v:channel}, rewritten as variables of the template context; the ids of the call are left as they are. The
+// values are resolved as text against the expression context of the call before the templates, so they are not interpolated again.
+func (rt requestTemplate) withCallerReferences(expressionCtx *expression.Context) (requestTemplate, map[string]interface{}, error) {
+	if expressionCtx == nil {
+		return rt, nil, nil
+	}
+
+	vars := make(map[string]interface{})
+	names := make(map[string]string)
+	var err error
+	rewrite := func(tmpl string) string {
+		return callerReferenceRegexp.ReplaceAllStringFunc(tmpl, func(ref string) string {
+			m := callerReferenceRegexp.FindStringSubmatch(ref)
+			switch m[2] {
+			case "v:" + TemplateVarActionId, "v:" + TemplateVarRequestId, "v:" + TemplateVarLraId:
+				return ref
+			}
+
+			n, ok := names[m[2]]
+			if !ok {
+				v, evalErr := expressionCtx.EvalOne(interpolateOnlyPrefix + "{" + m[2] + "}")
+				if evalErr != nil && err == nil {
+					err = fmt.Errorf("reference %s: %w", m[2], evalErr)
+				}
+
+				n = "caller-" + strconv.Itoa(len(names))
+				names[m[2]] = n
+				vars[n] = fmt.Sprint(v)
+			}
+
+			return "{" + m[1] + "v:" + n + "}"
+		})
+	}
+
+	if rt.bodyTemplate == BodyTemplateExpression {
+		rt.body = rewrite(rt.body)
+	}
+
+	rt.formParams = rewriteParams(rt.formParams, rewrite)
+	rt.queryParams = rewriteParams(rt.queryParams, rewrite)
+	return rt, vars, err
+}
+
+func rewriteParams(params []ParamDefinition, rewrite func(string) string) []ParamDefinition {
+	if len(params) == 0 {
+		return params
+	}
+
+	ps := make([]ParamDefinition, len(params))
+	for i, p := range params {
+		ps[i] = ParamDefinition{Name: p.Name, Value: rewrite(p.Value)}
+	}
+
+	return ps
+}
+
+// bodyContext the context of expression body templates. Xml bodies get the values xml escaped since the templates insert them as
+// they are.
+func (rt requestTemplate) bodyContext(tCtx *expression.Context, actionId string, reqId string, lraId string, callerVars map[string]interface{}, actionBody map[string]interface{}) (*expression.Context, error) {
+	if rt.body == "" || rt.bodyTemplate != BodyTemplateExpression || !isXmlContentType(rt.contentType) {
+		return tCtx, nil
+	}
+
+	vars, _ := xmlEscapeValue(callerVars).(map[string]interface{})
+	m, _ := xmlEscapeValue(actionBody).(map[string]interface{})
+	return templateContext(xmlEscapeString(actionId), xmlEscapeString(reqId), xmlEscapeString(lraId), vars, m)
+}
+
+func xmlEscapeString(s string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// xmlEscapeValue a copy of the value with the strings, nested ones included, escaped as xml text.
+func xmlEscapeValue(v interface{}) interface{} {
+	switch tv := v.(type) {
+	case string:
+		return xmlEscapeString(tv)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(tv))
+		for n, nv := range tv {
+			m[n] = xmlEscapeValue(nv)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(tv))
+		for i, av := range tv {
+			a[i] = xmlEscapeValue(av)
+		}
+		return a
+	}
+
+	return v
+}
+
+// requestBody the body of the request: the body template if any; else, for form-urlencoded, the form params or the top level fields
+// of the action body; else the action body as json.
+func (rt requestTemplate) requestBody(tCtx *expression.Context, expressionCtx *expression.Context, actionId string, actionBody map[string]interface{}) ([]byte, error) {
+	ct := rt.contentType
+	if ct == "" {
+		ct = ContentTypeApplicationJson
+	}
+
+	if rt.body != "" {
+		b, err := rt.resolveBody(tCtx, expressionCtx, actionId, actionBody)
+		if err != nil {
+			return nil, err
+		}
+
+		if ct == ContentTypeApplicationJson && !json.Valid(b) {
+			return nil, fmt.Errorf("action %s: body template does not resolve to valid json", actionId)
+		}
+
+		return b, nil
+	}
+
+	if ct == ContentTypeFormUrlEncoded {
+		form := url.Values{}
+		if len(rt.formParams) > 0 {
+			for _, p := range rt.formParams {
+				v, err := resolveParam(tCtx, p.Value)
+				if err != nil {
+					return nil, fmt.Errorf("action %s: form param %s: %w", actionId, p.Name, err)
+				}
+				form.Add(p.Name, v)
+			}
+		} else {
+			names := make([]string, 0, len(actionBody))
+			for n := range actionBody {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+
+			for _, n := range names {
+				form.Add(n, formValue(actionBody[n]))
+			}
+		}
+
+		return []byte(form.Encode()), nil
+	}
+
+	return json.Marshal(actionBody)
+}
+
+func (rt requestTemplate) resolveBody(tCtx *expression.Context, expressionCtx *expression.Context, actionId string, actionBody map[string]interface{}) ([]byte, error) {
+	if rt.bodyTemplate == BodyTemplateGo {
+		// the template is shared by the calls: the clone binds the functions to the expression context of this one.
+		tmpl, err := rt.goTemplate.Clone()
+		if err != nil {
+			return nil, fmt.Errorf("action %s: body template: %w", actionId, err)
+		}
+
+		var buf bytes.Buffer
+		if err = tmpl.Funcs(templateFuncs(expressionCtx)).Execute(&buf, actionBody); err != nil {
+			return nil, fmt.Errorf("action %s: body template: %w", actionId, err)
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	v, err := tCtx.EvalOne(interpolateOnlyPrefix + rt.body)
+	if err != nil {
+		return nil, fmt.Errorf("action %s: body template: %w", actionId, err)
+	}
+
+	return []byte(fmt.Sprint(v)), nil
+}
+
+func (rt requestTemplate) resolveQueryParams(tCtx *expression.Context, actionId string) ([]har.NameValuePair, error) {
+	var qps []har.NameValuePair
+	for _, p := range rt.queryParams {
+		v, err := resolveParam(tCtx, p.Value)
+		if err != nil {
+			return nil, fmt.Errorf("action %s: query param %s: %w", actionId, p.Name, err)
+		}
+		qps = append(qps, har.NameValuePair{Name: p.Name, Value: v})
+	}
+
+	return qps, nil
+}
+
+func resolveParam(tCtx *expression.Context, tmpl string) (string, error) {
+	v, err := tCtx.EvalOne(interpolateOnlyPrefix + tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprint(v), nil
+}
+
+func formValue(v interface{}) string {
+	switch tv := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return tv
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(tv)
+		return string(b)
+	}
+
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,112 @@
+package actionsclient_test
+
+import (
+	"encoding/json"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-common/util/expression"
+	actions "github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/actionsclient"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+type echoedRequest struct {
+	ContentType string `json:"content-type"`
+	Query       string `json:"query"`
+	Body        string `json:"body"`
+}
+
+func TestRequestBodyTemplates(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(echoedRequest{ContentType: r.Header.Get("Content-Type"), Query: r.URL.RawQuery, Body: string(b)})
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+	host := actions.HostInfo{Scheme: u.Scheme, HostName: u.Hostname(), Port: port}
+
+	const soapEnvelope = `<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope"><soap:Body><Check tenant="{{ eval "{h:x-tenant}" }}"><Name>{{ xml .name }}</Name></Check></soap:Body></soap:Envelope>`
+
+	lks, err := actions.NewInstanceWithConfig([]actions.Config{
+		{
+			Id: "json", Type: actions.ActionTypeEnrich, Host: host, Method: http.MethodPost, Path: "/json",
+			Body:        `{"customer": {"name": "{!$.name}"}, "request-id": "{v:request-id}", "amount": {$.amount}, "tenant": "{h:x-tenant}", "note": "{!v:note}"}`,
+			QueryParams: []actions.ParamDefinition{{Name: "channel", Value: "{$.channel}"}, {Name: "tenant", Value: "{h:x-tenant}"}},
+		},
+		{
+			Id: "form", Type: actions.ActionTypeEnrich, Host: host, Method: http.MethodPost, Path: "/form",
+			ContentType: actions.ContentTypeFormUrlEncoded,
+			FormParams:  []actions.ParamDefinition{{Name: "nome", Value: "{$.name}"}, {Name: "canale", Value: "{$.channel}"}, {Name: "tenant", Value: "{h:x-tenant}"}},
+		},
+		{Id: "form-body", Type: actions.ActionTypeEnrich, Host: host, Method: http.MethodPost, Path: "/form", ContentType: actions.ContentTypeFormUrlEncoded},
+		{Id: "soap", Type: actions.ActionTypeEnrich, Host: host, Method: http.MethodPost, Path: "/soap", ContentType: actions.ContentTypeSoapXml, BodyTemplate: actions.BodyTemplateGo, Body: soapEnvelope},
+		{Id: "xml", Type: actions.ActionTypeEnrich, Host: host, Method: http.MethodPost, Path: "/xml", ContentType: actions.ContentTypeTextXml, Body: `<check><channel>{$.channel}</channel><name>{$.name}</name><req>{v:request-id}</req><note>{v:note}</note></check>`},
+		{Id: "bad-json", Type: actions.ActionTypeEnrich, Host: host, Method: http.MethodPost, Path: "/json", Body: `{"name": {$.name}}`},
+	})
+	require.NoError(t, err)
+	defer lks.Close()
+
+	body := map[string]interface{}{"name": `Mario "Super" <Rossi>`, "amount": 10.5, "channel": "web app"}
+	// expression templates resolve headers and vars against the expression context of the call; the values are not interpolated again.
+	eCtx, err := expression.NewContext(
+		expression.WithHeaders([]expression.NameValuePair{{Name: "x-tenant", Value: "t1"}}),
+		expression.WithVars(map[string]interface{}{"note": `say "{$.name}" <now>`}))
+	require.NoError(t, err)
+
+	call := func(actionId string) echoedRequest {
+		m, err := lks.CallActionWithContext(actions.NewApiRequestContext(actions.ApiRequestWithRequestId("req-1")), actionId, eCtx, body)
+		require.NoError(t, err)
+		return echoedRequest{ContentType: m["content-type"].(string), Query: m["query"].(string), Body: m["body"].(string)}
+	}
+
+	r := call("json")
+	require.JSONEq(t, `{"customer": {"name": "Mario \"Super\" <Rossi>"}, "request-id": "req-1", "amount": 10.5, "tenant": "t1", "note": "say \"{$.name}\" <now>"}`, r.Body)
+	require.Equal(t, "channel=web+app&tenant=t1", r.Query)
+
+	r = call("form")
+	require.Equal(t, actions.ContentTypeFormUrlEncoded, r.ContentType)
+	require.Equal(t, "canale=web+app&nome=Mario+%22Super%22+%3CRossi%3E&tenant=t1", r.Body)
+
+	r = call("form-body")
+	require.Equal(t, "amount=10.5&channel=web+app&name=Mario+%22Super%22+%3CRossi%3E", r.Body)
+
+	r = call("soap")
+	require.Equal(t, actions.ContentTypeSoapXml, r.ContentType)
+	require.Equal(t, `<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope"><soap:Body><Check tenant="t1"><Name>Mario &#34;Super&#34; &lt;Rossi&gt;</Name></Check></soap:Body></soap:Envelope>`, r.Body)
+
+	r = call("xml")
+	require.Equal(t, actions.ContentTypeTextXml, r.ContentType)
+	require.Equal(t, `<check><channel>web app</channel><name>Mario &#34;Super&#34; &lt;Rossi&gt;</name><req>req-1</req><note>say &#34;{$.name}&#34; &lt;now&gt;</note></check>`, r.Body)
+
+	// the go template is parsed once: each call binds it to its own expression context.
+	for _, tenant := range []string{"t2", "t3"} {
+		tCtx, err := expression.NewContext(expression.WithHeaders([]expression.NameValuePair{{Name: "x-tenant", Value: tenant}}))
+		require.NoError(t, err)
+		m, err := lks.CallAction("soap", tCtx, body)
+		require.NoError(t, err)
+		require.Contains(t, m["body"], `<Check tenant="`+tenant+`">`)
+	}
+
+	_, err = lks.CallAction("bad-json", nil, body)
+	require.Error(t, err)
+
+	for _, cfg := range []actions.Config{
+		{Id: "xml-without-body", ContentType: actions.ContentTypeApplicationXml},
+		{Id: "unknown-content-type", ContentType: "text/csv"},
+		{Id: "form-params-json", FormParams: []actions.ParamDefinition{{Name: "a", Value: "b"}}},
+		{Id: "bad-go-template", BodyTemplate: actions.BodyTemplateGo, Body: "{{ .name "},
+		{Id: "unknown-template", BodyTemplate: "mustache", Body: "{}"},
+	} {
+		_, err = actions.NewInstanceWithConfig([]actions.Config{cfg})
+		require.Error(t, err, cfg.Id)
+	}
+}
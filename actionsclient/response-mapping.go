@@ -0,0 +1,222 @@
+package actionsclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-common/util/expression"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-archive/har"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/internal/jsonpathutil"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	MappingTypeString = "string"
+	MappingTypeInt    = "int"
+	MappingTypeFloat  = "float"
+	MappingTypeBool   = "bool"
+
+	SuccessConditionFailedErrorCode = "action-failed"
+)
+
+// ResponseMapping sets the variable Name (dot separated for nested objects) of the action result. Value is either a json path
+// reference (i.e. {$.data.customer-id}) that keeps the type of the referenced value or a template resolved against the response.
+// Default is used when the value is missing; Type, if set, converts the value.
+type ResponseMapping struct {
+	Name    string      `mapstructure:"name" json:"name" yaml:"name"`
+	Value   string      `mapstructure:"value" json:"value" yaml:"value"`
+	Default interface{} `mapstructure:"default,omitempty" json:"default,omitempty" yaml:"default,omitempty"`
+	Type    string      `mapstructure:"type,omitempty" json:"type,omitempty" yaml:"type,omitempty"`
+}
+
+// SuccessPredicate a condition a 200 response has to satisfy, i.e. "{$.esito}" == "OK". ErrorCode and Message are templates resolved
+// against the response to describe the failure.
+type SuccessPredicate struct {
+	Condition string `mapstructure:"condition" json:"condition" yaml:"condition"`
+	ErrorCode string `mapstructure:"error-code,omitempty" json:"error-code,omitempty" yaml:"error-code,omitempty"`
+	Message   string `mapstructure:"message,omitempty" json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+func validateResponseHandling(c *Config) error {
+	for _, m := range c.ResponseMapping {
+		if m.Name == "" || strings.HasPrefix(m.Name, ".") || strings.HasSuffix(m.Name, ".") {
+			return fmt.Errorf("action %s: invalid response mapping name %q", c.Id, m.Name)
+		}
+
+		switch m.Type {
+		case "", MappingTypeString, MappingTypeInt, MappingTypeFloat, MappingTypeBool:
+		default:
+			return fmt.Errorf("action %s: response mapping %s: unsupported type %s", c.Id, m.Name, m.Type)
+		}
+	}
+
+	for _, p := range c.Success {
+		if p.Condition == "" {
+			return fmt.Errorf("action %s: success predicate with empty condition", c.Id)
+		}
+	}
+
+	return nil
+}
+
+func responseBodyAsMap(harEntry *har.Entry) (map[string]interface{}, error) {
+	if harEntry.Response == nil || harEntry.Response.Content == nil || len(harEntry.Response.Content.Data) == 0 {
+		return nil, errors.New("no response body to evaluate")
+	}
+
+	m := make(map[string]interface{})
+	if err := json.Unmarshal(harEntry.Response.Content.Data, &m); err != nil {
+		return nil, fmt.Errorf("error unmarshalling response of content-type: %s", harEntry.Response.Content.MimeType)
+	}
+
+	return m, nil
+}
+
+// checkSuccess evaluates the predicates in order: the first one not satisfied, or not evaluable, fails the action.
+func checkSuccess(predicates []SuccessPredicate, resp map[string]interface{}) error {
+	if len(predicates) == 0 {
+		return nil
+	}
+
+	eCtx, err := expression.NewContext(expression.WithMapInput(resp))
+	if err != nil {
+		return err
+	}
+
+	for _, p := range predicates {
+		ok, err := eCtx.BoolEvalOne(p.Condition)
+		if err == nil && ok {
+			continue
+		}
+
+		ar := &ActionResponse{
+			StatusCode: http.StatusUnprocessableEntity,
+			ErrCode:    resolveTemplate(eCtx, p.ErrorCode, SuccessConditionFailedErrorCode),
+			Message:    resolveTemplate(eCtx, p.Message, "success condition not satisfied: "+p.Condition),
+			Ts:         time.Now().Format(time.RFC3339Nano),
+		}
+		if err != nil {
+			ar.Description = err.Error()
+		}
+		return ar
+	}
+
+	return nil
+}
+
+func resolveTemplate(eCtx *expression.Context, tmpl string, def string) string {
+	if tmpl == "" {
+		return def
+	}
+
+	v, err := eCtx.EvalOne(tmpl)
+	if err != nil || v == nil || fmt.Sprint(v) == "" {
+		return def
+	}
+
+	return fmt.Sprint(v)
+}
+
+// mapResponse builds the action result from the mappings. Values missing and without default are not set.
+func mapResponse(mappings []ResponseMapping, resp map[string]interface{}) (map[string]interface{}, error) {
+	eCtx, err := expression.NewContext(expression.WithMapInput(resp))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]interface{})
+	for _, m := range mappings {
+		var v interface{}
+		if p, ok := jsonpathutil.SingleReference(m.Value); ok {
+			v, err = jsonpathutil.Get(p, resp)
+			if err != nil {
+				return nil, fmt.Errorf("response mapping of %s: %w", m.Name, err)
+			}
+		} else {
+			v, err = eCtx.EvalOne(m.Value)
+			if err != nil {
+				return nil, fmt.Errorf("response mapping of %s: %w", m.Name, err)
+			}
+
+			if s, ok := v.(string); ok && s == "" {
+				v = nil
+			}
+		}
+
+		if v == nil {
+			v = m.Default
+		}
+
+		if v == nil {
+			continue
+		}
+
+		v, err = coerceMappedValue(v, m.Type)
+		if err != nil {
+			return nil, fmt.Errorf("response mapping of %s: %w", m.Name, err)
+		}
+
+		jsonpathutil.SetField(out, m.Name, v)
+	}
+
+	return out, nil
+}
+
+func coerceMappedValue(v interface{}, typ string) (interface{}, error) {
+	switch typ {
+	case MappingTypeString:
+		if f, ok := v.(float64); ok {
+			return strconv.FormatFloat(f, 'f', -1, 64), nil
+		}
+		return fmt.Sprint(v), nil
+
+	case MappingTypeInt:
+		switch tv := v.(type) {
+		case float64:
+			if tv == math.Trunc(tv) {
+				return int64(tv), nil
+			}
+		case int:
+			return int64(tv), nil
+		case int64:
+			return tv, nil
+		case string:
+			if i, err := strconv.ParseInt(strings.TrimSpace(tv), 10, 64); err == nil {
+				return i, nil
+			}
+		}
+
+	case MappingTypeFloat:
+		switch tv := v.(type) {
+		case float64:
+			return tv, nil
+		case int:
+			return float64(tv), nil
+		case int64:
+			return float64(tv), nil
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(tv), 64); err == nil {
+				return f, nil
+			}
+		}
+
+	case MappingTypeBool:
+		switch tv := v.(type) {
+		case bool:
+			return tv, nil
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(tv)); err == nil {
+				return b, nil
+			}
+		}
+
+	default:
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("cannot convert %v to %s", v, typ)
+}
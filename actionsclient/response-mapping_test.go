@@ -0,0 +1,78 @@
+package actionsclient_test
+
+import (
+	"errors"
+	actions "github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/actionsclient"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestResponseMapping(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/ok":
+			_, _ = w.Write([]byte(`{"esito": "OK", "data": {"customer-id": "c1", "code": "A7", "score": "42", "flags": {"vip": "true"}}, "history": [1, 2, 3]}`))
+		case "/ko":
+			_, _ = w.Write([]byte(`{"esito": "KO", "codice": "E01", "descrizione": "customer blocked"}`))
+		default:
+			_, _ = w.Write([]byte(`{"esito": "KO"}`))
+		}
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+	host := actions.HostInfo{Scheme: u.Scheme, HostName: u.Hostname(), Port: port}
+
+	success := []actions.SuccessPredicate{{Condition: `"{$.esito}" == "OK"`, ErrorCode: "{$.codice}", Message: "{$.descrizione}"}}
+	mapping := []actions.ResponseMapping{
+		{Name: "customer.id", Value: "{$.data.customer-id}"},
+		{Name: "customer.label", Value: "cust-{$.data.code}"},
+		{Name: "score", Value: "{$.data.score}", Type: actions.MappingTypeInt},
+		{Name: "vip", Value: "{$.data.flags.vip}", Type: actions.MappingTypeBool},
+		{Name: "channel", Value: "{$.channel}", Default: "web"},
+		{Name: "missing", Value: "{$.missing}"},
+	}
+
+	lks, err := actions.NewInstanceWithConfig([]actions.Config{
+		{Id: "enrich-ok", Type: actions.ActionTypeEnrich, Host: host, Method: http.MethodPost, Path: "/ok", ResponseMapping: mapping, Success: success},
+		{Id: "enrich-ko", Type: actions.ActionTypeEnrich, Host: host, Method: http.MethodPost, Path: "/ko", ResponseMapping: mapping, Success: success},
+		{Id: "check-ko", Type: actions.ActionTypeBool, Host: host, Method: http.MethodPost, Path: "/other", Success: success},
+	})
+	require.NoError(t, err)
+	defer lks.Close()
+
+	m, err := lks.CallAction("enrich-ok", nil, map[string]interface{}{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"customer": map[string]interface{}{"id": "c1", "label": "cust-A7"},
+		"score":    int64(42),
+		"vip":      true,
+		"channel":  "web",
+	}, m)
+
+	_, err = lks.CallAction("enrich-ko", nil, map[string]interface{}{})
+	var ar *actions.ActionResponse
+	require.True(t, errors.As(err, &ar))
+	require.Equal(t, http.StatusUnprocessableEntity, ar.StatusCode)
+	require.Equal(t, "E01", ar.ErrCode)
+	require.Equal(t, "customer blocked", ar.Message)
+
+	_, err = lks.CallAction("check-ko", nil, map[string]interface{}{})
+	require.True(t, errors.As(err, &ar))
+	require.Equal(t, actions.SuccessConditionFailedErrorCode, ar.ErrCode)
+
+	_, err = actions.NewInstanceWithConfig([]actions.Config{{Id: "bad", ResponseMapping: []actions.ResponseMapping{{Name: "x", Value: "{$.x}", Type: "date"}}}})
+	require.Error(t, err)
+
+	_, err = actions.NewInstanceWithConfig([]actions.Config{{Id: "bad", Success: []actions.SuccessPredicate{{}}}})
+	require.Error(t, err)
+}
@@ -0,0 +1,211 @@
+package actionsclient
+
+import (
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-common/util"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-common/util/expression"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-client/restclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+// SagaLraIdVar the variable of the expression context holding the LRA id of the saga, i.e. to pass it in the body of the actions.
+// Actions called through a ContextActionCaller get it in the Long-Running-Action header as well.
+const SagaLraIdVar = "lra-id"
+
+type SagaOutcome string
+
+const (
+	SagaOutcomeCommitted SagaOutcome = "committed"
+	// SagaOutcomeAborted the transition has not been taken: nothing to compensate.
+	SagaOutcomeAborted SagaOutcome = "aborted"
+	// SagaOutcomeCompensated the executed actions have been compensated and the token rolled back.
+	SagaOutcomeCompensated SagaOutcome = "compensated"
+	// SagaOutcomeCompensationFailed some compensation or the rollback failed: external systems and token may be out of sync.
+	SagaOutcomeCompensationFailed SagaOutcome = "compensation-failed"
+)
+
+// SagaActions the actions of a saga and their compensations. LinkedService implements it.
+type SagaActions interface {
+	ActionCaller
+	CompensationOf(actionId string) (string, bool)
+}
+
+// SagaTokens the token operations of a saga. tokensclient.Client implements it.
+type SagaTokens interface {
+	TakeTransition(reqCtx tokensclient.ApiRequestContext, ctxId string, tokId string, transitionName string, tokenRequest *tokensclient.TokenApiRequest, ct string) (*token.Token, error)
+	CommitToken(reqCtx tokensclient.ApiRequestContext, ctxId string, tokId string) (*token.Token, error)
+	RollbackToken(reqCtx tokensclient.ApiRequestContext, ctxId string, tokId string) (*token.Token, error)
+}
+
+// SagaDefinition the token transition and the actions of a saga. An empty transition name takes the next transition of the token.
+type SagaDefinition struct {
+	CtxId        string
+	TokId        string
+	Transition   string
+	TokenRequest *tokensclient.TokenApiRequest
+	ContentType  string
+	Actions      []token.Action
+}
+
+type SagaReport struct {
+	LraId         string                 `yaml:"lra-id,omitempty" mapstructure:"lra-id,omitempty" json:"lra-id,omitempty"`
+	Outcome       SagaOutcome            `yaml:"outcome,omitempty" mapstructure:"outcome,omitempty" json:"outcome,omitempty"`
+	Token         *token.Token           `yaml:"token,omitempty" mapstructure:"token,omitempty" json:"token,omitempty"`
+	Actions       *PipelineReport        `yaml:"actions,omitempty" mapstructure:"actions,omitempty" json:"actions,omitempty"`
+	Compensations []ActionReport         `yaml:"compensations,omitempty" mapstructure:"compensations,omitempty" json:"compensations,omitempty"`
+	RollbackError string                 `yaml:"rollback-error,omitempty" mapstructure:"rollback-error,omitempty" json:"rollback-error,omitempty"`
+	Body          map[string]interface{} `yaml:"-" mapstructure:"-" json:"-"`
+}
+
+// Saga executes a token transition and a sequence of actions as a unit correlated by the LRA id of the request context: the
+// transition is taken first, then the actions are executed and the token committed. If an action or the commit fails the
+// actions already executed are compensated in reverse order and the token is rolled back.
+type Saga struct {
+	actions      SagaActions
+	tokens       SagaTokens
+	pipelineOpts []PipelineOption
+}
+
+type SagaOption func(s *Saga)
+
+// SagaWithPipelineOptions options of the pipeline executing the actions; the error policy is always stop on error.
+func SagaWithPipelineOptions(opts ...PipelineOption) SagaOption {
+	return func(s *Saga) {
+		s.pipelineOpts = append(s.pipelineOpts, opts...)
+	}
+}
+
+func NewSaga(actions SagaActions, tokens SagaTokens, opts ...SagaOption) *Saga {
+	s := &Saga{actions: actions, tokens: tokens}
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s
+}
+
+func (lks *LinkedService) NewSaga(tokens SagaTokens, opts ...SagaOption) *Saga {
+	return NewSaga(lks, tokens, opts...)
+}
+
+// Execute runs the saga. A new LRA id is generated if the request context has none. The error returned is the one that caused the
+// failure, the report tells how far the saga got and the result of the compensations.
+func (s *Saga) Execute(reqCtx tokensclient.ApiRequestContext, def SagaDefinition, expressionCtx *expression.Context, body map[string]interface{}, opts ...restclient.Option) (*SagaReport, error) {
+	const semLogContext = semLogContextBase + "::saga-execute"
+
+	if reqCtx.LRAId == "" {
+		reqCtx.LRAId = util.NewObjectId().String()
+	}
+
+	report := &SagaReport{LraId: reqCtx.LRAId, Body: body}
+	if expressionCtx == nil {
+		var err error
+		expressionCtx, err = expression.NewContext()
+		if err != nil {
+			return nil, err
+		}
+	}
+	_ = expressionCtx.SetVar(SagaLraIdVar, reqCtx.LRAId)
+
+	var caller ActionCaller = s.actions
+	if cc, ok := s.actions.(ContextActionCaller); ok {
+		caller = BindRequestContext(cc, ApiRequestContext{RequestId: reqCtx.RequestId, LRAId: reqCtx.LRAId, Span: reqCtx.Span, HarSpan: reqCtx.HarSpan})
+	}
+
+	tokenRequest := def.TokenRequest
+	if tokenRequest == nil {
+		tokenRequest = &tokensclient.TokenApiRequest{TokenId: def.TokId}
+	}
+
+	tok, err := s.tokens.TakeTransition(reqCtx, def.CtxId, def.TokId, def.Transition, tokenRequest, def.ContentType)
+	if err != nil {
+		log.Error().Err(err).Str("lra-id", reqCtx.LRAId).Str("transition", def.Transition).Msg(semLogContext + " transition failed")
+		report.Outcome = SagaOutcomeAborted
+		return report, err
+	}
+	report.Token = tok
+
+	report.Actions, err = s.pipeline(caller).Execute(def.Actions, expressionCtx, body, opts...)
+	if report.Actions != nil {
+		report.Body = report.Actions.Body
+	}
+
+	if err == nil {
+		tok, err = s.tokens.CommitToken(reqCtx, def.CtxId, def.TokId)
+		if err == nil {
+			log.Info().Str("lra-id", reqCtx.LRAId).Msg(semLogContext + " committed")
+			report.Token = tok
+			report.Outcome = SagaOutcomeCommitted
+			return report, nil
+		}
+		log.Error().Err(err).Str("lra-id", reqCtx.LRAId).Msg(semLogContext + " commit failed")
+	}
+
+	report.Outcome = SagaOutcomeCompensated
+	if !s.compensate(caller, report, def.Actions, expressionCtx, opts...) {
+		report.Outcome = SagaOutcomeCompensationFailed
+	}
+
+	tok, rbErr := s.tokens.RollbackToken(reqCtx, def.CtxId, def.TokId)
+	if rbErr != nil {
+		log.Error().Err(rbErr).Str("lra-id", reqCtx.LRAId).Msg(semLogContext + " rollback failed")
+		report.RollbackError = rbErr.Error()
+		report.Outcome = SagaOutcomeCompensationFailed
+	} else {
+		report.Token = tok
+	}
+
+	return report, err
+}
+
+func (s *Saga) pipeline(caller ActionCaller) *Pipeline {
+	p := NewPipeline(caller, s.pipelineOpts...)
+	p.opts.OnError = StopOnError
+	for id, ao := range p.opts.Actions {
+		ao.OnError = StopOnError
+		p.opts.Actions[id] = ao
+	}
+
+	return p
+}
+
+// compensate calls, in reverse order, the compensations of the actions executed successfully with the saga body plus the properties
+// of the action. A failed compensation does not stop the others.
+func (s *Saga) compensate(caller ActionCaller, report *SagaReport, acts []token.Action, expressionCtx *expression.Context, opts ...restclient.Option) bool {
+	const semLogContext = semLogContextBase + "::saga-compensate"
+
+	if report.Actions == nil {
+		return true
+	}
+
+	ok := true
+	for i := len(report.Actions.Actions) - 1; i >= 0; i-- {
+		ar := report.Actions.Actions[i]
+		if ar.Outcome != ActionOutcomeOk {
+			continue
+		}
+
+		compId, has := s.actions.CompensationOf(ar.ActionId)
+		if !has {
+			continue
+		}
+
+		cr := ActionReport{ActionId: compId, Outcome: ActionOutcomeOk}
+		start := time.Now()
+		resp, err := caller.CallAction(compId, expressionCtx, actionBody(report.Body, acts[i].Properties), opts...)
+		cr.Duration = time.Since(start)
+		if err != nil {
+			log.Error().Err(err).Str("lra-id", report.LraId).Str("action-id", ar.ActionId).Str("compensation", compId).Msg(semLogContext)
+			cr.Outcome, cr.Err, cr.Error = ActionOutcomeFailed, err, err.Error()
+			ok = false
+		} else {
+			cr.Response = resp
+		}
+
+		report.Compensations = append(report.Compensations, cr)
+	}
+
+	return ok
+}
@@ -0,0 +1,141 @@
+package actionsclient_test
+
+import (
+	"errors"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-common/util/expression"
+	actions "github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/actionsclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+var (
+	_ actions.SagaTokens  = (*tokensclient.Client)(nil)
+	_ actions.SagaActions = (*actions.LinkedService)(nil)
+)
+
+type sagaActions struct {
+	callerFunc
+	compensations map[string]string
+}
+
+func (sa sagaActions) CompensationOf(actionId string) (string, bool) {
+	c, ok := sa.compensations[actionId]
+	return c, ok
+}
+
+type sagaTokens struct {
+	ops        []string
+	lraIds     []string
+	failNext   bool
+	failCommit bool
+}
+
+func (st *sagaTokens) TakeTransition(reqCtx tokensclient.ApiRequestContext, ctxId string, tokId string, transitionName string, _ *tokensclient.TokenApiRequest, _ string) (*token.Token, error) {
+	st.ops, st.lraIds = append(st.ops, "next:"+transitionName), append(st.lraIds, reqCtx.LRAId)
+	if st.failNext {
+		return nil, errors.New("transition not available")
+	}
+	return &token.Token{Id: tokId}, nil
+}
+
+func (st *sagaTokens) CommitToken(reqCtx tokensclient.ApiRequestContext, ctxId string, tokId string) (*token.Token, error) {
+	st.ops, st.lraIds = append(st.ops, "commit"), append(st.lraIds, reqCtx.LRAId)
+	if st.failCommit {
+		return nil, errors.New("commit failed")
+	}
+	return &token.Token{Id: tokId}, nil
+}
+
+func (st *sagaTokens) RollbackToken(reqCtx tokensclient.ApiRequestContext, ctxId string, tokId string) (*token.Token, error) {
+	st.ops, st.lraIds = append(st.ops, "rollback"), append(st.lraIds, reqCtx.LRAId)
+	return &token.Token{Id: tokId}, nil
+}
+
+func TestSaga(t *testing.T) {
+
+	var calls []string
+	sa := sagaActions{
+		callerFunc: func(actionId string, body map[string]interface{}) (map[string]interface{}, error) {
+			calls = append(calls, actionId)
+			switch actionId {
+			case "book":
+				return map[string]interface{}{"booking-id": "b1"}, nil
+			case "cancel-booking":
+				if body["booking-id"] != "b1" {
+					return nil, errors.New("booking id missing")
+				}
+			case "charge":
+				return nil, errors.New("charge refused")
+			case "notify-compensation":
+				return nil, errors.New("notify unavailable")
+			}
+			return nil, nil
+		},
+		compensations: map[string]string{"book": "cancel-booking", "notify": "notify-compensation", "charge": "refund"},
+	}
+
+	def := actions.SagaDefinition{CtxId: "ctx", TokId: "tok", Transition: "redeem", Actions: []token.Action{{ActionId: "book"}, {ActionId: "notify"}}}
+
+	st := &sagaTokens{}
+	report, err := actions.NewSaga(sa, st).Execute(tokensclient.NewApiRequestContext(tokensclient.ApiRequestWithLraId("lra-1")), def, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, actions.SagaOutcomeCommitted, report.Outcome)
+	require.Equal(t, []string{"next:redeem", "commit"}, st.ops)
+	require.Equal(t, []string{"lra-1", "lra-1"}, st.lraIds)
+	require.Equal(t, []string{"book", "notify"}, calls)
+	require.Equal(t, "b1", report.Body["booking-id"])
+
+	// charge fails: notify and book are compensated in reverse order, the failure of notify-compensation does not stop the others.
+	calls, st = nil, &sagaTokens{}
+	eCtx, err := expression.NewContext()
+	require.NoError(t, err)
+
+	def.Actions = append(def.Actions, token.Action{ActionId: "charge"})
+	report, err = actions.NewSaga(sa, st).Execute(tokensclient.NewApiRequestContext(), def, eCtx, nil)
+	require.EqualError(t, err, "charge refused")
+	require.Equal(t, actions.SagaOutcomeCompensationFailed, report.Outcome)
+	require.Equal(t, []string{"book", "notify", "charge", "notify-compensation", "cancel-booking"}, calls)
+	require.Equal(t, []string{"next:redeem", "rollback"}, st.ops)
+	require.NotEmpty(t, report.LraId)
+	require.Equal(t, report.LraId, st.lraIds[1])
+	lraId, err := eCtx.EvalOne("{v:" + actions.SagaLraIdVar + "}")
+	require.NoError(t, err)
+	require.Equal(t, report.LraId, lraId)
+	require.Len(t, report.Compensations, 2)
+	require.Equal(t, actions.ActionOutcomeFailed, report.Compensations[0].Outcome)
+	require.Equal(t, actions.ActionOutcomeOk, report.Compensations[1].Outcome)
+
+	// commit fails: every action is compensated.
+	calls, st = nil, &sagaTokens{failCommit: true}
+	def.Actions = def.Actions[:1]
+	report, err = actions.NewSaga(sa, st).Execute(tokensclient.NewApiRequestContext(), def, nil, nil)
+	require.EqualError(t, err, "commit failed")
+	require.Equal(t, actions.SagaOutcomeCompensated, report.Outcome)
+	require.Equal(t, []string{"book", "cancel-booking"}, calls)
+	require.Equal(t, []string{"next:redeem", "commit", "rollback"}, st.ops)
+
+	// transition not taken: no action and nothing to undo.
+	calls, st = nil, &sagaTokens{failNext: true}
+	report, err = actions.NewSaga(sa, st).Execute(tokensclient.NewApiRequestContext(), def, nil, nil)
+	require.Error(t, err)
+	require.Equal(t, actions.SagaOutcomeAborted, report.Outcome)
+	require.Empty(t, calls)
+	require.Equal(t, []string{"next:redeem"}, st.ops)
+}
+
+func TestCompensationConfig(t *testing.T) {
+	_, err := actions.NewInstanceWithConfig([]actions.Config{{Id: "book", Compensation: "cancel-booking"}})
+	require.Error(t, err)
+
+	lks, err := actions.NewInstanceWithConfig([]actions.Config{{Id: "book", Compensation: "cancel-booking"}, {Id: "cancel-booking"}})
+	require.NoError(t, err)
+
+	c, ok := lks.CompensationOf("book")
+	require.True(t, ok)
+	require.Equal(t, "cancel-booking", c)
+
+	_, ok = lks.CompensationOf("cancel-booking")
+	require.False(t, ok)
+}
@@ -26,6 +26,12 @@ func (c *Client) Close() {
 
 func NewClient(cfg *Config, opts ...restclient.Option) (*Client, error) {
 	const semLogContext = "bridge-client::new"
+
+	if err := validateEndpoints(cfg.Endpoints, RequiredEndpoints); err != nil {
+		log.Error().Err(err).Msg(semLogContext)
+		return nil, err
+	}
+
 	client := restclient.NewClient(&cfg.Config, opts...)
 
 	h := cfg.Host
@@ -52,14 +58,14 @@ func NewClient(cfg *Config, opts ...restclient.Option) (*Client, error) {
 	return &Client{client: client, host: h, endpoints: cfg.Endpoints}, nil
 }
 
-func (cli *Client) findEndpointPathById(endpointId string) string {
+func (cli *Client) findEndpointById(endpointId string) (EndpointDefinition, bool) {
 
 	endpointId = strings.ToLower(endpointId)
 	for _, ep := range cli.endpoints {
-		if ep.Id == endpointId {
-			return ep.Url
+		if strings.ToLower(ep.Id) == endpointId {
+			return ep, true
 		}
 	}
 
-	return ""
+	return EndpointDefinition{}, false
 }
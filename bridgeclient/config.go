@@ -6,11 +6,22 @@ import (
 
 const (
 	TokenContextIdPathPlaceHolder = "{context-id}"
+	TokenIdPathPlaceHolder        = "{token-id}"
 )
 
+// EndpointDefinition an endpoint of the bridged system. Url, query param values and body are templates: see Resolve for the placeholders supported.
+// Method defaults to POST, ContentType to application/json; with no body template the default request of the operation, possibly mapped, is sent.
 type EndpointDefinition struct {
-	Id  string `mapstructure:"id" json:"id" yaml:"id"`
-	Url string `mapstructure:"url" json:"url" yaml:"url"`
+	Id          string                 `mapstructure:"id" json:"id" yaml:"id"`
+	Method      string                 `mapstructure:"method,omitempty" json:"method,omitempty" yaml:"method,omitempty"`
+	Url         string                 `mapstructure:"url" json:"url" yaml:"url"`
+	QueryParams []QueryParamDefinition `mapstructure:"query-params,omitempty" json:"query-params,omitempty" yaml:"query-params,omitempty"`
+	ContentType string                 `mapstructure:"content-type,omitempty" json:"content-type,omitempty" yaml:"content-type,omitempty"`
+	Body        string                 `mapstructure:"body,omitempty" json:"body,omitempty" yaml:"body,omitempty"`
+	// RequestMapping builds the body from the default request of the operation when no body template is given.
+	RequestMapping []FieldMapping `mapstructure:"request-mapping,omitempty" json:"request-mapping,omitempty" yaml:"request-mapping,omitempty"`
+	// ResponseMapping builds the response of the operation from the one of the bridged system.
+	ResponseMapping []FieldMapping `mapstructure:"response-mapping,omitempty" json:"response-mapping,omitempty" yaml:"response-mapping,omitempty"`
 }
 
 type HostInfo struct {
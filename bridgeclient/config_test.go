@@ -35,9 +35,17 @@ var cliConfig = bridgeclient.Config{
 	},
 	Endpoints: []bridgeclient.EndpointDefinition{
 		{
-			Id:  "new-id",
+			Id:  bridgeclient.NewTokenIdEndpointId,
 			Url: "/api/v1/campaigns/{context-id}",
 		},
+		{
+			Id:  bridgeclient.RetrieveTokenEndpointId,
+			Url: "/api/v1/campaigns/{context-id}/tokens/{token-id}",
+		},
+		{
+			Id:  bridgeclient.UpdateTokenEndpointId,
+			Url: "/api/v1/campaigns/{context-id}/tokens/{token-id}",
+		},
 	},
 }
 
@@ -0,0 +1,289 @@
+package bridgeclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-common/util/expression"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-archive/har"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-client/restclient"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// interpolateOnlyPrefix keeps the expression context from evaluating the resolved template as an expression when it contains
+	// operators, as urls with query strings and json bodies do.
+	interpolateOnlyPrefix = "!e:"
+
+	EndpointParamContextId = "context-id"
+	EndpointParamTokenId   = "token-id"
+	EndpointParamUnique    = "unique"
+)
+
+// RequiredEndpoints the endpoints a bridge configuration has to provide.
+var RequiredEndpoints = []string{NewTokenIdEndpointId, RetrieveTokenEndpointId, UpdateTokenEndpointId}
+
+// QueryParamDefinition a query parameter of an endpoint. The value is a template resolved as the url.
+type QueryParamDefinition struct {
+	Name  string `mapstructure:"name" json:"name" yaml:"name"`
+	Value string `mapstructure:"value,omitempty" json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// ResolvedEndpoint an endpoint definition with the templates resolved against the params of an operation.
+type ResolvedEndpoint struct {
+	Id              string
+	Method          string
+	Path            string
+	QueryParams     []har.NameValuePair
+	ContentType     string
+	Body            []byte
+	RequestMapping  []FieldMapping
+	ResponseMapping []FieldMapping
+	params          map[string]string
+}
+
+// Validate checks the definition is usable: the id is set, the method is a known one and the url is a path whose
+// placeholders are balanced.
+func (ep *EndpointDefinition) Validate() error {
+	if ep.Id == "" {
+		return errors.New("bridge endpoint with empty id")
+	}
+
+	switch strings.ToUpper(ep.Method) {
+	case "", http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return fmt.Errorf("bridge endpoint %s: unsupported method %s", ep.Id, ep.Method)
+	}
+
+	if !strings.HasPrefix(ep.Url, "/") {
+		return fmt.Errorf("bridge endpoint %s: url %q is not an absolute path", ep.Id, ep.Url)
+	}
+
+	if strings.Count(ep.Url, "{") != strings.Count(ep.Url, "}") {
+		return fmt.Errorf("bridge endpoint %s: unbalanced placeholders in url %q", ep.Id, ep.Url)
+	}
+
+	for _, qp := range ep.QueryParams {
+		if qp.Name == "" {
+			return fmt.Errorf("bridge endpoint %s: query param with empty name", ep.Id)
+		}
+	}
+
+	for _, m := range append(append([]FieldMapping{}, ep.RequestMapping...), ep.ResponseMapping...) {
+		if m.Name == "" || strings.HasPrefix(m.Name, ".") || strings.HasSuffix(m.Name, ".") {
+			return fmt.Errorf("bridge endpoint %s: invalid mapping field name %q", ep.Id, m.Name)
+		}
+	}
+
+	return nil
+}
+
+// Resolve resolves the url, the query params and the body of the endpoint. Templates are interpolated through an expression context where
+// params are variables and the properties of the operation are the input ({$.cf}, {!$.cf} to json escape in bodies). Plain placeholders
+// named after params (i.e. {context-id}) stand for the variables ({v:context-id}); param values are path escaped in the url, json escaped
+// in json bodies and inserted as they are, never resolved themselves.
+// An empty body template leaves Body nil so that the operation sends its default request, mapped by the RequestMapping if any.
+func (ep *EndpointDefinition) Resolve(params map[string]string, props map[string]interface{}) (ResolvedEndpoint, error) {
+
+	rep := ResolvedEndpoint{
+		Id:              ep.Id,
+		Method:          strings.ToUpper(ep.Method),
+		ContentType:     ep.ContentType,
+		RequestMapping:  ep.RequestMapping,
+		ResponseMapping: ep.ResponseMapping,
+		params:          params,
+	}
+	if rep.Method == "" {
+		rep.Method = http.MethodPost
+	}
+
+	if rep.ContentType == "" {
+		rep.ContentType = ContentTypeApplicationJson
+	}
+
+	var err error
+	rep.Path, err = resolveEndpointTemplate(ep.Url, params, url.PathEscape, props)
+	if err != nil {
+		return rep, fmt.Errorf("bridge endpoint %s: %w", ep.Id, err)
+	}
+
+	for _, qp := range ep.QueryParams {
+		v, err := resolveEndpointTemplate(qp.Value, params, nil, props)
+		if err != nil {
+			return rep, fmt.Errorf("bridge endpoint %s: query param %s: %w", ep.Id, qp.Name, err)
+		}
+		rep.QueryParams = append(rep.QueryParams, har.NameValuePair{Name: qp.Name, Value: v})
+	}
+
+	if ep.Body != "" {
+		var escape func(string) string
+		if strings.Contains(rep.ContentType, "json") {
+			escape = jsonEscape
+		}
+
+		b, err := resolveEndpointTemplate(ep.Body, params, escape, props)
+		if err != nil {
+			return rep, fmt.Errorf("bridge endpoint %s: body: %w", ep.Id, err)
+		}
+		rep.Body = []byte(b)
+	}
+
+	return rep, nil
+}
+
+// endpointParamsAsVariables rewrites the plain placeholders named after params as variable references: {token-id} becomes {v:token-id}.
+func endpointParamsAsVariables(tmpl string, params map[string]string) string {
+	for n := range params {
+		tmpl = strings.ReplaceAll(tmpl, "{"+n+"}", "{v:"+n+"}")
+	}
+
+	return tmpl
+}
+
+// endpointExpressionContext the context templates are resolved against: the params, escaped if requested, as variables and the
+// properties as input.
+func endpointExpressionContext(params map[string]string, escape func(string) string, props map[string]interface{}) (*expression.Context, error) {
+	vars := make(map[string]interface{})
+	for n, v := range params {
+		if escape != nil {
+			v = escape(v)
+		}
+		vars[n] = v
+	}
+
+	return expression.NewContext(expression.WithVars(vars), expression.WithMapInput(props))
+}
+
+// jsonEscape escapes the value for use inside a json string.
+func jsonEscape(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b[1 : len(b)-1])
+}
+
+// resolveEndpointTemplate interpolates the template: the result is never evaluated as an expression.
+func resolveEndpointTemplate(tmpl string, params map[string]string, escape func(string) string, props map[string]interface{}) (string, error) {
+	eCtx, err := endpointExpressionContext(params, escape, props)
+	if err != nil {
+		return "", err
+	}
+
+	v, err := eCtx.EvalOne(interpolateOnlyPrefix + endpointParamsAsVariables(tmpl, params))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprint(v), nil
+}
+
+// validateEndpoints checks the definitions and the presence of the required endpoints. Ids are matched case insensitive.
+func validateEndpoints(eps []EndpointDefinition, required []string) error {
+	ids := make(map[string]struct{})
+	for i := range eps {
+		if err := eps[i].Validate(); err != nil {
+			return err
+		}
+
+		id := strings.ToLower(eps[i].Id)
+		if _, ok := ids[id]; ok {
+			return fmt.Errorf("bridge endpoint %s: duplicate definition", eps[i].Id)
+		}
+		ids[id] = struct{}{}
+	}
+
+	var missing []string
+	for _, id := range required {
+		if _, ok := ids[strings.ToLower(id)]; !ok {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("bridge endpoints not configured: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+func (c *Client) resolveEndpoint(endpointId string, params map[string]string, props map[string]interface{}) (ResolvedEndpoint, error) {
+	ep, ok := c.findEndpointById(endpointId)
+	if !ok {
+		return ResolvedEndpoint{}, NewBadRequestError(WithErrorMessage(fmt.Sprintf("bridge endpoint %s not configured", endpointId)))
+	}
+
+	rep, err := ep.Resolve(params, props)
+	if err != nil {
+		return rep, NewBadRequestError(WithErrorMessage(err.Error()))
+	}
+
+	return rep, nil
+}
+
+func (c *Client) resolvedEndpointUrl(rep ResolvedEndpoint) string {
+	var sb = strings.Builder{}
+	sb.WriteString(c.host.Scheme)
+	sb.WriteString("://")
+	sb.WriteString(c.host.HostName)
+	sb.WriteString(":")
+	sb.WriteString(fmt.Sprint(c.host.Port))
+	sb.WriteString(rep.Path)
+
+	if len(rep.QueryParams) > 0 {
+		if strings.Contains(rep.Path, "?") {
+			sb.WriteString("&")
+		} else {
+			sb.WriteString("?")
+		}
+		for i, qp := range rep.QueryParams {
+			if i > 0 {
+				sb.WriteString("&")
+			}
+			sb.WriteString(url.QueryEscape(qp.Name))
+			sb.WriteString("=")
+			sb.WriteString(url.QueryEscape(qp.Value))
+		}
+	}
+	return sb.String()
+}
+
+// execute resolves the endpoint, sends the request (the default one unless the endpoint defines a body or a request mapping) and
+// returns the response entry with the response mapping of the endpoint applied. The endpoint id is the operation name of the traces.
+func (c *Client) execute(reqCtx ApiRequestContext, endpointId string, params map[string]string, props map[string]interface{}, request interface{}) (*har.Entry, error) {
+	const semLogContext = "bridge-client::execute"
+
+	rep, err := c.resolveEndpoint(endpointId, params, props)
+	if err != nil {
+		log.Error().Err(err).Str("endpoint-id", endpointId).Msg(semLogContext + " unresolved endpoint")
+		return nil, err
+	}
+
+	b, err := rep.requestBody(request)
+	if err != nil {
+		return nil, NewBadRequestError(WithErrorMessage(err.Error()))
+	}
+
+	req, err := c.client.NewRequest(rep.Method, c.resolvedEndpointUrl(rep), b, reqCtx.getHeaders(rep.ContentType), nil)
+	if err != nil {
+		return nil, NewBadRequestError(WithErrorMessage(err.Error()))
+	}
+
+	harEntry, err := c.client.Execute(req,
+		restclient.ExecutionWithOpName(endpointId),
+		restclient.ExecutionWithRequestId(reqCtx.RequestId),
+		restclient.ExecutionWithSpan(reqCtx.Span),
+		restclient.ExecutionWithHarSpan(reqCtx.HarSpan))
+	// c.harEntries = append(c.harEntries, harEntry)
+	if err != nil {
+		return nil, NewExecutableServerError(WithErrorMessage(err.Error()))
+	}
+
+	harEntry, err = rep.mapResponse(harEntry)
+	if err != nil {
+		log.Error().Err(err).Str("endpoint-id", endpointId).Msg(semLogContext)
+		return nil, NewExecutableServerError(WithErrorMessage(err.Error()))
+	}
+
+	return harEntry, nil
+}
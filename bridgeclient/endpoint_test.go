@@ -0,0 +1,177 @@
+package bridgeclient_test
+
+import (
+	"encoding/json"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/bridgeclient"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestEndpointResolve(t *testing.T) {
+
+	ep := bridgeclient.EndpointDefinition{
+		Id:          bridgeclient.RetrieveTokenEndpointId,
+		Method:      "get",
+		Url:         "/legacy/{context-id}/coupons/{v:token-id}",
+		QueryParams: []bridgeclient.QueryParamDefinition{{Name: "fiscal-code", Value: "{$.cf}"}},
+		Body:        `{"ctx": "{context-id}", "cf": "{!$.cf}"}`,
+	}
+	require.NoError(t, ep.Validate())
+
+	rep, err := ep.Resolve(map[string]string{bridgeclient.EndpointParamContextId: "BPM/1", bridgeclient.EndpointParamTokenId: "TOK1"}, map[string]interface{}{"cf": "MPRMLS62S21G337J"})
+	require.NoError(t, err)
+	require.Equal(t, http.MethodGet, rep.Method)
+	require.Equal(t, "/legacy/BPM%2F1/coupons/TOK1", rep.Path)
+	require.Equal(t, "MPRMLS62S21G337J", rep.QueryParams[0].Value)
+	require.JSONEq(t, `{"ctx": "BPM/1", "cf": "MPRMLS62S21G337J"}`, string(rep.Body))
+
+	require.Error(t, (&bridgeclient.EndpointDefinition{Id: "x", Url: "/a/{context-id"}).Validate())
+
+	// values with operators are interpolated, never evaluated; plain params in json bodies are escaped.
+	ep = bridgeclient.EndpointDefinition{
+		Id:          bridgeclient.RetrieveTokenEndpointId,
+		Url:         "/legacy/{context-id}/coupons?filter=a<b",
+		QueryParams: []bridgeclient.QueryParamDefinition{{Name: "q", Value: "{$.q}"}},
+		Body:        `{"ctx": "{context-id}", "tok": "{token-id}"}`,
+	}
+	rep, err = ep.Resolve(map[string]string{bridgeclient.EndpointParamContextId: "C(1)", bridgeclient.EndpointParamTokenId: `T"1`}, map[string]interface{}{"q": "a=b"})
+	require.NoError(t, err)
+	require.Equal(t, "/legacy/C%281%29/coupons?filter=a<b", rep.Path)
+	require.Equal(t, "a=b", rep.QueryParams[0].Value)
+	require.JSONEq(t, `{"ctx": "C(1)", "tok": "T\"1"}`, string(rep.Body))
+
+	// param values are inserted as they are: references in them are not resolved.
+	ep = bridgeclient.EndpointDefinition{
+		Id:          bridgeclient.RetrieveTokenEndpointId,
+		Url:         "/legacy/{context-id}/coupons/{token-id}",
+		QueryParams: []bridgeclient.QueryParamDefinition{{Name: "tok", Value: "{token-id}"}},
+		Body:        `{"tok": "{token-id}", "also": "{v:token-id}"}`,
+	}
+	rep, err = ep.Resolve(map[string]string{bridgeclient.EndpointParamContextId: "C1", bridgeclient.EndpointParamTokenId: "AB{$.secret}"}, map[string]interface{}{"secret": "PWD"})
+	require.NoError(t, err)
+	require.Equal(t, "/legacy/C1/coupons/AB%7B$.secret%7D", rep.Path)
+	require.Equal(t, "AB{$.secret}", rep.QueryParams[0].Value)
+	require.JSONEq(t, `{"tok": "AB{$.secret}", "also": "AB{$.secret}"}`, string(rep.Body))
+}
+
+func TestNewClientEndpoints(t *testing.T) {
+
+	var gotPath, gotQuery, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotQuery = r.URL.EscapedPath(), r.URL.RawQuery
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"token-id": "TOK1"}`))
+	}))
+	defer srv.Close()
+
+	cfg := newBridgeTestConfig(srv,
+		bridgeclient.EndpointDefinition{Id: bridgeclient.NewTokenIdEndpointId, Url: "/ids/{context-id}", QueryParams: []bridgeclient.QueryParamDefinition{{Name: "unique", Value: "{unique}"}}},
+	)
+
+	_, err := bridgeclient.NewClient(&cfg)
+	require.Error(t, err)
+
+	cfg.Endpoints = append(cfg.Endpoints,
+		bridgeclient.EndpointDefinition{Id: bridgeclient.RetrieveTokenEndpointId, Url: "/tokens/{token-id}"},
+		bridgeclient.EndpointDefinition{Id: "Update-Token", Method: http.MethodPut, Url: "/tokens/{token-id}"},
+	)
+	cli, err := bridgeclient.NewClient(&cfg)
+	require.NoError(t, err)
+	defer cli.Close()
+
+	resp, err := cli.NewId(bridgeclient.NewApiRequestContext(bridgeclient.ApiRequestWithApiKey("test")), "BPMGM1", true, map[string]interface{}{"cf": "MPRMLS62S21G337J"})
+	require.NoError(t, err)
+	require.Equal(t, "TOK1", resp.Id)
+	require.Equal(t, "/ids/BPMGM1", gotPath)
+	require.Equal(t, "unique=true", gotQuery)
+	require.Contains(t, gotBody, "MPRMLS62S21G337J")
+}
+
+func TestBridgeMappings(t *testing.T) {
+
+	var gotBody map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody = nil
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"result": {"code": "TOK1", "state": "ACTIVE", "attrs": {"cf": "MPRMLS62S21G337J"}}, "created": "20230501", "retries": 2}`))
+	}))
+	defer srv.Close()
+
+	cfg := newBridgeTestConfig(srv,
+		bridgeclient.EndpointDefinition{Id: bridgeclient.NewTokenIdEndpointId, Url: "/ids"},
+		bridgeclient.EndpointDefinition{Id: bridgeclient.RetrieveTokenEndpointId, Url: "/tokens/{token-id}"},
+		bridgeclient.EndpointDefinition{
+			Id:  bridgeclient.UpdateTokenEndpointId,
+			Url: "/tokens/{token-id}",
+			RequestMapping: []bridgeclient.FieldMapping{
+				{Name: "coupon.code", Value: "{$.token-id}"},
+				{Name: "coupon.campaign", Value: "{v:context-id}"},
+				{Name: "ref", Value: "{token-id}"},
+				{Name: "single", Value: "{$.unique}"},
+				{Name: "data", Value: "{$.properties}"},
+			},
+			ResponseMapping: []bridgeclient.FieldMapping{
+				{Name: "token-id", Value: "{$.result.code}"},
+				{Name: "creation-date", Value: "{$.created}"},
+				{Name: "status", Value: "{$.result.state}"},
+				{Name: "properties", Value: "{$.result.attrs}"},
+				{Name: "retries", Value: "{$.retries}"},
+			},
+		},
+	)
+
+	cli, err := bridgeclient.NewClient(&cfg)
+	require.NoError(t, err)
+	defer cli.Close()
+
+	reqCtx := bridgeclient.NewApiRequestContext(bridgeclient.ApiRequestWithApiKey("test"))
+	_, err = cli.RetrieveToken(reqCtx, "BPMGM1", "TOK1", true, nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"context-id": "BPMGM1", "token-id": "TOK1", "unique": true}, gotBody)
+
+	resp, err := cli.UpdateToken(reqCtx, "BPMGM1", "TOK1", true, map[string]interface{}{"cf": "MPRMLS62S21G337J"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"coupon": map[string]interface{}{"code": "TOK1", "campaign": "BPMGM1"}, "ref": "TOK1", "single": true, "data": map[string]interface{}{"cf": "MPRMLS62S21G337J"}}, gotBody)
+
+	// params in mappings are not resolved either.
+	_, err = cli.UpdateToken(reqCtx, "BPMGM1", "AB{$.properties.cf}", true, map[string]interface{}{"cf": "MPRMLS62S21G337J"})
+	require.NoError(t, err)
+	require.Equal(t, "BPMGM1", gotBody["coupon"].(map[string]interface{})["campaign"])
+	require.Equal(t, "AB{$.properties.cf}", gotBody["ref"])
+	require.Equal(t, "TOK1", resp.Id)
+	require.Equal(t, "20230501", resp.CreationDate)
+	require.Equal(t, "ACTIVE", resp.Status())
+	require.Equal(t, "MPRMLS62S21G337J", resp.Properties()["cf"])
+	n, ok := resp.Extensions.Int("retries")
+	require.True(t, ok)
+	require.Equal(t, int64(2), n)
+
+	b, err := json.Marshal(resp)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"status":"ACTIVE"`)
+
+	// numeric ids of legacy systems are kept as strings, a non string creation date stays in the extensions.
+	var numResp bridgeclient.TokenResponse
+	require.NoError(t, json.Unmarshal([]byte(`{"token-id": 12345678901234567890, "creation-date": 20230501}`), &numResp))
+	require.Equal(t, "12345678901234567890", numResp.Id)
+	require.Equal(t, "", numResp.CreationDate)
+	require.True(t, numResp.Extensions.Has("creation-date"))
+	require.False(t, numResp.Extensions.Has("token-id"))
+}
+
+func newBridgeTestConfig(srv *httptest.Server, eps ...bridgeclient.EndpointDefinition) bridgeclient.Config {
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+	return bridgeclient.Config{
+		Host:      bridgeclient.HostInfo{Scheme: "http", HostName: u.Hostname(), Port: port},
+		Endpoints: eps,
+	}
+}
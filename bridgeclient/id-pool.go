@@ -0,0 +1,270 @@
+package bridgeclient
+
+import (
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	IdPoolDefaultLowWatermark  = 100
+	IdPoolDefaultHighWatermark = 1000
+	IdPoolDefaultRetryInterval = time.Second
+	IdPoolDefaultWaitTimeout   = 5 * time.Second
+)
+
+var (
+	ErrIdPoolExhausted = errors.New("token id pool exhausted")
+	ErrIdPoolClosed    = errors.New("token id pool closed")
+)
+
+// IdReserver the batch operations the pool relies on, implemented by Client.
+type IdReserver interface {
+	ReserveIds(reqCtx ApiRequestContext, ctxId string, count int, unique bool, act map[string]interface{}) (*ReserveTokenIdsResponse, error)
+	ReleaseIds(reqCtx ApiRequestContext, ctxId string, ids []string) (*ReleaseTokenIdsResponse, error)
+	SupportsIdRelease() bool
+}
+
+// IdPoolConfig when the pool drops below LowWatermark ids it is refilled up to HighWatermark in reservations of at most BatchSize ids.
+// Failed reservations are retried every RetryInterval; Get waits at most WaitTimeout for an id when the pool is empty.
+type IdPoolConfig struct {
+	ContextId     string                 `yaml:"context-id,omitempty" mapstructure:"context-id,omitempty" json:"context-id,omitempty"`
+	Unique        bool                   `yaml:"unique,omitempty" mapstructure:"unique,omitempty" json:"unique,omitempty"`
+	LowWatermark  int                    `yaml:"low-watermark,omitempty" mapstructure:"low-watermark,omitempty" json:"low-watermark,omitempty"`
+	HighWatermark int                    `yaml:"high-watermark,omitempty" mapstructure:"high-watermark,omitempty" json:"high-watermark,omitempty"`
+	BatchSize     int                    `yaml:"batch-size,omitempty" mapstructure:"batch-size,omitempty" json:"batch-size,omitempty"`
+	RetryInterval time.Duration          `yaml:"retry-interval,omitempty" mapstructure:"retry-interval,omitempty" json:"retry-interval,omitempty"`
+	WaitTimeout   time.Duration          `yaml:"wait-timeout,omitempty" mapstructure:"wait-timeout,omitempty" json:"wait-timeout,omitempty"`
+	Properties    map[string]interface{} `yaml:"properties,omitempty" mapstructure:"properties,omitempty" json:"properties,omitempty"`
+}
+
+// IdPool pre-fetches ids of a context from the bridge in the background and hands them out to the token creation.
+type IdPool struct {
+	cfg      IdPoolConfig
+	reserver IdReserver
+	reqOpts  []APIRequestContextOption
+
+	ids       chan string
+	refill    chan struct{}
+	quit      chan struct{}
+	wg        sync.WaitGroup
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+// NewIdPool the options are applied to the request context of the reservations; a request id is generated for each call.
+func NewIdPool(reserver IdReserver, cfg IdPoolConfig, reqOpts ...APIRequestContextOption) (*IdPool, error) {
+	if cfg.ContextId == "" {
+		return nil, errors.New("token id pool requires a context id")
+	}
+
+	if cfg.HighWatermark <= 0 {
+		cfg.HighWatermark = IdPoolDefaultHighWatermark
+	}
+
+	if cfg.LowWatermark <= 0 {
+		cfg.LowWatermark = IdPoolDefaultLowWatermark
+	}
+
+	if cfg.LowWatermark > cfg.HighWatermark {
+		return nil, fmt.Errorf("token id pool low watermark %d greater than high watermark %d", cfg.LowWatermark, cfg.HighWatermark)
+	}
+
+	if cfg.BatchSize <= 0 || cfg.BatchSize > cfg.HighWatermark {
+		cfg.BatchSize = cfg.HighWatermark
+	}
+
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = IdPoolDefaultRetryInterval
+	}
+
+	if cfg.WaitTimeout <= 0 {
+		cfg.WaitTimeout = IdPoolDefaultWaitTimeout
+	}
+
+	p := &IdPool{
+		cfg:      cfg,
+		reserver: reserver,
+		reqOpts:  reqOpts,
+		ids:      make(chan string, cfg.HighWatermark),
+		refill:   make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+	}
+
+	return p, nil
+}
+
+func (p *IdPool) Start() {
+	p.startOnce.Do(func() {
+		p.wg.Add(1)
+		go p.run()
+	})
+}
+
+// Stop terminates the pre-fetching and gives the ids left in the pool back to the bridge if it supports the release, otherwise they are discarded.
+func (p *IdPool) Stop() {
+	const semLogContext = "bridge-client::id-pool-stop"
+
+	p.stopOnce.Do(func() {
+		close(p.quit)
+		p.wg.Wait()
+
+		var unused []string
+		for done := false; !done; {
+			select {
+			case id := <-p.ids:
+				unused = append(unused, id)
+			default:
+				done = true
+			}
+		}
+
+		if len(unused) == 0 {
+			return
+		}
+
+		if !p.reserver.SupportsIdRelease() {
+			log.Info().Str("ctx-id", p.cfg.ContextId).Int("unused", len(unused)).Msg(semLogContext + " release not supported... unused ids discarded")
+			return
+		}
+
+		resp, err := p.reserver.ReleaseIds(p.requestContext(), p.cfg.ContextId, unused)
+		if err != nil {
+			log.Error().Err(err).Str("ctx-id", p.cfg.ContextId).Int("unused", len(unused)).Msg(semLogContext)
+			return
+		}
+
+		log.Info().Str("ctx-id", p.cfg.ContextId).Int("unused", len(unused)).Int("released", resp.Released).Msg(semLogContext)
+	})
+}
+
+// Len the number of ids available.
+func (p *IdPool) Len() int {
+	return len(p.ids)
+}
+
+// Get an id of the pool, waiting for a refill if the pool is empty.
+func (p *IdPool) Get() (string, error) {
+	select {
+	case <-p.quit:
+		return "", ErrIdPoolClosed
+	default:
+	}
+
+	select {
+	case id := <-p.ids:
+		p.checkWatermark()
+		return id, nil
+	default:
+	}
+
+	p.triggerRefill()
+	timer := time.NewTimer(p.cfg.WaitTimeout)
+	defer timer.Stop()
+
+	select {
+	case id := <-p.ids:
+		p.checkWatermark()
+		return id, nil
+	case <-p.quit:
+		return "", ErrIdPoolClosed
+	case <-timer.C:
+		return "", ErrIdPoolExhausted
+	}
+}
+
+// NewId makes the pool a token id provider of its context. The ids are prefetched with the uniqueness and the properties of the pool
+// configuration: asking for a different uniqueness is an error and the action properties are not applied.
+func (p *IdPool) NewId(ctxId string, unique bool, action map[string]interface{}) (string, error) {
+	if !strings.EqualFold(ctxId, p.cfg.ContextId) {
+		return "", fmt.Errorf("token id pool of context %s cannot provide ids of context %s", p.cfg.ContextId, ctxId)
+	}
+
+	if unique != p.cfg.Unique {
+		return "", fmt.Errorf("token id pool of context %s provides ids with unique %t, requested %t", p.cfg.ContextId, p.cfg.Unique, unique)
+	}
+
+	return p.Get()
+}
+
+func (p *IdPool) checkWatermark() {
+	if len(p.ids) < p.cfg.LowWatermark {
+		p.triggerRefill()
+	}
+}
+
+func (p *IdPool) triggerRefill() {
+	select {
+	case p.refill <- struct{}{}:
+	default:
+	}
+}
+
+func (p *IdPool) run() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.cfg.RetryInterval)
+	defer ticker.Stop()
+
+	for {
+		p.fill()
+		select {
+		case <-p.quit:
+			return
+		case <-p.refill:
+		case <-ticker.C:
+		}
+	}
+}
+
+// fill tops the pool up to the high watermark once it went below the low one.
+func (p *IdPool) fill() {
+	const semLogContext = "bridge-client::id-pool-fill"
+
+	if len(p.ids) >= p.cfg.LowWatermark {
+		return
+	}
+
+	for n := p.cfg.HighWatermark - len(p.ids); n > 0; n = p.cfg.HighWatermark - len(p.ids) {
+		select {
+		case <-p.quit:
+			return
+		default:
+		}
+
+		if n > p.cfg.BatchSize {
+			n = p.cfg.BatchSize
+		}
+
+		resp, err := p.reserver.ReserveIds(p.requestContext(), p.cfg.ContextId, n, p.cfg.Unique, p.cfg.Properties)
+		if err != nil {
+			log.Error().Err(err).Str("ctx-id", p.cfg.ContextId).Int("count", n).Msg(semLogContext)
+			return
+		}
+
+		if len(resp.Ids) == 0 {
+			log.Warn().Str("ctx-id", p.cfg.ContextId).Int("count", n).Msg(semLogContext + " no ids reserved")
+			return
+		}
+
+		for _, id := range resp.Ids {
+			select {
+			case p.ids <- id:
+			default:
+				// The bridge returned more than requested: the exceeding ids are not usable.
+				log.Warn().Str("ctx-id", p.cfg.ContextId).Str("token-id", id).Msg(semLogContext + " pool full... id discarded")
+			}
+		}
+
+		log.Trace().Str("ctx-id", p.cfg.ContextId).Int("reserved", len(resp.Ids)).Int("available", len(p.ids)).Msg(semLogContext)
+	}
+}
+
+func (p *IdPool) requestContext() ApiRequestContext {
+	reqCtx := NewApiRequestContext(p.reqOpts...)
+	ApiRequestWithAutoRequestId()(&reqCtx)
+	return reqCtx
+}
@@ -0,0 +1,80 @@
+package bridgeclient_test
+
+import (
+	"fmt"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/bridgeclient"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryReserver struct {
+	mu       sync.Mutex
+	next     int
+	calls    int
+	released []string
+}
+
+func (r *memoryReserver) ReserveIds(reqCtx bridgeclient.ApiRequestContext, ctxId string, count int, unique bool, act map[string]interface{}) (*bridgeclient.ReserveTokenIdsResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls++
+	resp := &bridgeclient.ReserveTokenIdsResponse{}
+	for i := 0; i < count; i++ {
+		r.next++
+		resp.Ids = append(resp.Ids, fmt.Sprintf("%s%06d", ctxId, r.next))
+	}
+	return resp, nil
+}
+
+func (r *memoryReserver) ReleaseIds(reqCtx bridgeclient.ApiRequestContext, ctxId string, ids []string) (*bridgeclient.ReleaseTokenIdsResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.released = append(r.released, ids...)
+	return &bridgeclient.ReleaseTokenIdsResponse{Released: len(ids)}, nil
+}
+
+func (r *memoryReserver) SupportsIdRelease() bool {
+	return true
+}
+
+func TestIdPool(t *testing.T) {
+
+	r := &memoryReserver{}
+	p, err := bridgeclient.NewIdPool(r, bridgeclient.IdPoolConfig{ContextId: "BPMGM1", Unique: true, LowWatermark: 5, HighWatermark: 10, BatchSize: 4, WaitTimeout: time.Second})
+	require.NoError(t, err)
+	p.Start()
+
+	seen := make(map[string]struct{})
+	for i := 0; i < 25; i++ {
+		id, err := p.NewId("bpmgm1", true, nil)
+		require.NoError(t, err)
+		seen[id] = struct{}{}
+	}
+	require.Len(t, seen, 25)
+
+	_, err = p.NewId("OTHER1", true, nil)
+	require.Error(t, err)
+
+	// the uniqueness is the one of the pool.
+	_, err = p.NewId("BPMGM1", false, nil)
+	require.Error(t, err)
+
+	p.Stop()
+	_, err = p.Get()
+	require.ErrorIs(t, err, bridgeclient.ErrIdPoolClosed)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	require.Equal(t, r.next-25, len(r.released))
+	for _, id := range r.released {
+		_, ok := seen[id]
+		require.False(t, ok)
+	}
+
+	_, err = bridgeclient.NewIdPool(r, bridgeclient.IdPoolConfig{ContextId: "BPMGM1", LowWatermark: 20, HighWatermark: 10})
+	require.Error(t, err)
+}
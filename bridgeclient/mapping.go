@@ -0,0 +1,104 @@
+package bridgeclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-archive/har"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/internal/jsonpathutil"
+	"net/http"
+)
+
+// FieldMapping sets the field Name (dot separated for nested objects) to the value of a template. A template made of a single
+// json path reference (i.e. {$.data.properties}, dashed names allowed) keeps the type of the referenced value, any other template
+// is resolved as the endpoint ones and yields a string or the result of an expression.
+type FieldMapping struct {
+	Name  string `mapstructure:"name" json:"name" yaml:"name"`
+	Value string `mapstructure:"value" json:"value" yaml:"value"`
+}
+
+// mapFields builds the object described by the mappings evaluating the templates against input, params are available as variables.
+func mapFields(mappings []FieldMapping, params map[string]string, input map[string]interface{}) (map[string]interface{}, error) {
+	eCtx, err := endpointExpressionContext(params, nil, input)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]interface{})
+	for _, m := range mappings {
+		var v interface{}
+		if p, ok := jsonpathutil.SingleReference(m.Value); ok {
+			v, err = jsonpathutil.Get(p, input)
+			if err != nil {
+				return nil, fmt.Errorf("mapping of %s: %w", m.Name, err)
+			}
+		} else {
+			v, err = eCtx.EvalOne(endpointParamsAsVariables(m.Value, params))
+			if err != nil {
+				return nil, fmt.Errorf("mapping of %s: %w", m.Name, err)
+			}
+		}
+
+		if v != nil {
+			jsonpathutil.SetField(out, m.Name, v)
+		}
+	}
+
+	return out, nil
+}
+
+// requestBody the body of the operation: the resolved body template if any, the request mapping applied to the default request
+// or the default request itself.
+func (rep *ResolvedEndpoint) requestBody(request interface{}) ([]byte, error) {
+	if rep.Body != nil {
+		return rep.Body, nil
+	}
+
+	b, err := json.Marshal(request)
+	if err != nil || len(rep.RequestMapping) == 0 {
+		return b, err
+	}
+
+	var input map[string]interface{}
+	if err = json.Unmarshal(b, &input); err != nil {
+		return nil, err
+	}
+
+	m, err := mapFields(rep.RequestMapping, rep.params, input)
+	if err != nil {
+		return nil, fmt.Errorf("bridge endpoint %s: request %w", rep.Id, err)
+	}
+
+	return json.Marshal(m)
+}
+
+// mapResponse applies the response mapping to a successful response. The entry is copied so that the traced one keeps the
+// original content.
+func (rep *ResolvedEndpoint) mapResponse(e *har.Entry) (*har.Entry, error) {
+	if len(rep.ResponseMapping) == 0 || e == nil || e.Response == nil || e.Response.Status != http.StatusOK || e.Response.Content == nil || len(e.Response.Content.Data) == 0 {
+		return e, nil
+	}
+
+	var input map[string]interface{}
+	if err := json.Unmarshal(e.Response.Content.Data, &input); err != nil {
+		return nil, fmt.Errorf("bridge endpoint %s: response is not a json object: %w", rep.Id, err)
+	}
+
+	m, err := mapFields(rep.ResponseMapping, rep.params, input)
+	if err != nil {
+		return nil, fmt.Errorf("bridge endpoint %s: response %w", rep.Id, err)
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	mapped := *e
+	resp := *e.Response
+	content := *e.Response.Content
+	content.Data = b
+	content.Size = int64(len(b))
+	resp.Content = &content
+	mapped.Response = &resp
+	return &mapped, nil
+}
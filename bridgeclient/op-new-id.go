@@ -1,15 +1,8 @@
 package bridgeclient
 
 import (
-	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-archive/har"
-	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-client/restclient"
-	"github.com/rs/zerolog/log"
-	"net/http"
-	"net/url"
-	"strings"
 )
 
 /*
@@ -32,42 +25,19 @@ func (req *NewTokenRequest) IsValid() bool {
 }
 
 type NewTokenResponse struct {
-	Id           string `yaml:"token-id,omitempty" mapstructure:"token-id,omitempty" json:"token-id,omitempty"`
-	CreationDate string `yaml:"creation-date,omitempty" mapstructure:"creation-date,omitempty" json:"creation-date,omitempty"`
+	TokenResponse `mapstructure:",squash"  yaml:",inline"`
 }
 
 func (c *Client) NewId(reqCtx ApiRequestContext, ctxId string, unique bool, act map[string]interface{}) (*NewTokenResponse, error) {
-	const semLogContext = "bridge-client::new-id"
-
-	urlPath := c.findEndpointPathById(NewTokenIdEndpointId)
-	if urlPath == "" {
-		log.Error().Msg(semLogContext + " unresolved endpoint url path")
-	}
-
-	ep := c.NewTokenIdUrl(urlPath, ctxId, nil)
-	ct := ContentTypeApplicationJson
-
 	newTokenRequest := NewTokenRequest{
-		Properties: act,
-	}
-	b, err := json.Marshal(newTokenRequest)
-	if err != nil {
-		return nil, NewBadRequestError(WithErrorMessage(err.Error()))
-	}
-
-	req, err := c.client.NewRequest(http.MethodPost, ep, b, reqCtx.getHeaders(ct), nil)
-	if err != nil {
-		return nil, NewBadRequestError(WithErrorMessage(err.Error()))
+		TokenContextId: ctxId,
+		Unique:         unique,
+		Properties:     act,
 	}
 
-	harEntry, err := c.client.Execute(req,
-		restclient.ExecutionWithOpName(RetrieveTokenEndpointId),
-		restclient.ExecutionWithRequestId(reqCtx.RequestId),
-		restclient.ExecutionWithSpan(reqCtx.Span),
-		restclient.ExecutionWithHarSpan(reqCtx.HarSpan))
-	// c.harEntries = append(c.harEntries, harEntry)
+	harEntry, err := c.execute(reqCtx, NewTokenIdEndpointId, map[string]string{EndpointParamContextId: ctxId, EndpointParamUnique: fmt.Sprint(unique)}, act, &newTokenRequest)
 	if err != nil {
-		return nil, NewExecutableServerError(WithErrorMessage(err.Error()))
+		return nil, err
 	}
 
 	resp, err := DeserializeNewTokenIdResponseBody(harEntry)
@@ -77,55 +47,10 @@ func (c *Client) NewId(reqCtx ApiRequestContext, ctxId string, unique bool, act
 func DeserializeNewTokenIdResponseBody(resp *har.Entry) (*NewTokenResponse, error) {
 
 	const semLogContext = "bridge-client::new-id-deserialize-response"
-	if resp == nil || resp.Response == nil || resp.Response.Content == nil || resp.Response.Content.Data == nil {
-		err := errors.New("cannot deserialize null response")
-		log.Error().Err(err).Msg(semLogContext)
-		return nil, NewExecutableServerError(WithErrorMessage(err.Error()))
-	}
-
-	var resultObj *NewTokenResponse
-	var err error
-	switch resp.Response.Status {
-	case http.StatusOK:
-		resultObj = &NewTokenResponse{}
-		err = json.Unmarshal(resp.Response.Content.Data, resultObj)
-		if err != nil {
-			return nil, NewExecutableServerError(WithErrorMessage(err.Error()))
-		}
-
-	default:
-		var apiResponse ApiResponse
-		apiResponse, err = DeserApiResponseFromJson(resp.Response.Content.Data)
-		if err != nil {
-			return nil, NewExecutableServerError(WithErrorMessage(err.Error()))
-		}
-		apiResponse.StatusCode = resp.Response.Status
-		err = &apiResponse
+	resultObj := &NewTokenResponse{}
+	if err := deserializeResponseBody(semLogContext, resp, resultObj); err != nil {
 		return nil, err
 	}
 
 	return resultObj, nil
 }
-
-func (c *Client) NewTokenIdUrl(apiPath string, ctxId string, qParams []har.NameValuePair) string {
-	var sb = strings.Builder{}
-	sb.WriteString(c.host.Scheme)
-	sb.WriteString("://")
-	sb.WriteString(c.host.HostName)
-	sb.WriteString(":")
-	sb.WriteString(fmt.Sprint(c.host.Port))
-	sb.WriteString(strings.Replace(apiPath, "{context-id}", ctxId, 1))
-
-	if len(qParams) > 0 {
-		sb.WriteString("?")
-		for i, qp := range qParams {
-			if i > 0 {
-				sb.WriteString("&")
-			}
-			sb.WriteString(qp.Name)
-			sb.WriteString("=")
-			sb.WriteString(url.QueryEscape(qp.Value))
-		}
-	}
-	return sb.String()
-}
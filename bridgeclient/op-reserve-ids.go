@@ -0,0 +1,120 @@
+package bridgeclient
+
+import (
+	"errors"
+	"fmt"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-archive/har"
+	"strconv"
+)
+
+/*
+ * Batch reservation of ids: optional endpoints, not all the bridged systems support them.
+ */
+
+const (
+	ReserveTokenIdsEndpointId = "reserve-tokens"
+	ReleaseTokenIdsEndpointId = "release-tokens"
+
+	EndpointParamCount = "count"
+)
+
+var ErrIdReleaseNotSupported = errors.New("bridge does not support the release of token ids")
+
+type ReserveTokenIdsRequest struct {
+	TokenContextId string                 `mapstructure:"context-id,omitempty"  json:"context-id,omitempty" yaml:"context-id,omitempty"`
+	Count          int                    `mapstructure:"count"  json:"count" yaml:"count"`
+	Unique         bool                   `mapstructure:"unique"  json:"unique" yaml:"unique"`
+	Properties     map[string]interface{} `mapstructure:"properties,omitempty"  json:"properties,omitempty" yaml:"custom,omitempty"`
+}
+
+type ReserveTokenIdsResponse struct {
+	Ids          []string `yaml:"token-ids,omitempty" mapstructure:"token-ids,omitempty" json:"token-ids,omitempty"`
+	CreationDate string   `yaml:"creation-date,omitempty" mapstructure:"creation-date,omitempty" json:"creation-date,omitempty"`
+}
+
+type ReleaseTokenIdsRequest struct {
+	TokenContextId string   `mapstructure:"context-id,omitempty"  json:"context-id,omitempty" yaml:"context-id,omitempty"`
+	Ids            []string `mapstructure:"token-ids,omitempty"  json:"token-ids,omitempty" yaml:"token-ids,omitempty"`
+}
+
+type ReleaseTokenIdsResponse struct {
+	Released int `yaml:"released,omitempty" mapstructure:"released,omitempty" json:"released,omitempty"`
+}
+
+// SupportsIdReservation the reserve-tokens endpoint is configured.
+func (c *Client) SupportsIdReservation() bool {
+	_, ok := c.findEndpointById(ReserveTokenIdsEndpointId)
+	return ok
+}
+
+// SupportsIdRelease the release-tokens endpoint is configured.
+func (c *Client) SupportsIdRelease() bool {
+	_, ok := c.findEndpointById(ReleaseTokenIdsEndpointId)
+	return ok
+}
+
+// ReserveIds reserves count ids of the context in one call. The bridge may return fewer ids than requested.
+func (c *Client) ReserveIds(reqCtx ApiRequestContext, ctxId string, count int, unique bool, act map[string]interface{}) (*ReserveTokenIdsResponse, error) {
+	if count <= 0 {
+		return nil, NewBadRequestError(WithErrorMessage(fmt.Sprintf("invalid number of ids to reserve: %d", count)))
+	}
+
+	reserveRequest := ReserveTokenIdsRequest{
+		TokenContextId: ctxId,
+		Count:          count,
+		Unique:         unique,
+		Properties:     act,
+	}
+
+	params := map[string]string{EndpointParamContextId: ctxId, EndpointParamUnique: fmt.Sprint(unique), EndpointParamCount: strconv.Itoa(count)}
+	harEntry, err := c.execute(reqCtx, ReserveTokenIdsEndpointId, params, act, &reserveRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := DeserializeReserveTokenIdsResponseBody(harEntry)
+	return resp, err
+}
+
+// ReleaseIds gives back to the bridge ids reserved and not used. ErrIdReleaseNotSupported if the endpoint is not configured.
+func (c *Client) ReleaseIds(reqCtx ApiRequestContext, ctxId string, ids []string) (*ReleaseTokenIdsResponse, error) {
+	if !c.SupportsIdRelease() {
+		return nil, ErrIdReleaseNotSupported
+	}
+
+	releaseRequest := ReleaseTokenIdsRequest{
+		TokenContextId: ctxId,
+		Ids:            ids,
+	}
+
+	params := map[string]string{EndpointParamContextId: ctxId, EndpointParamCount: strconv.Itoa(len(ids))}
+	harEntry, err := c.execute(reqCtx, ReleaseTokenIdsEndpointId, params, nil, &releaseRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := DeserializeReleaseTokenIdsResponseBody(harEntry)
+	return resp, err
+}
+
+func DeserializeReserveTokenIdsResponseBody(resp *har.Entry) (*ReserveTokenIdsResponse, error) {
+
+	const semLogContext = "bridge-client::reserve-ids-deserialize-response"
+	resultObj := &ReserveTokenIdsResponse{}
+	if err := deserializeResponseBody(semLogContext, resp, resultObj); err != nil {
+		return nil, err
+	}
+
+	return resultObj, nil
+}
+
+func DeserializeReleaseTokenIdsResponseBody(resp *har.Entry) (*ReleaseTokenIdsResponse, error) {
+
+	const semLogContext = "bridge-client::release-ids-deserialize-response"
+	resultObj := &ReleaseTokenIdsResponse{}
+	if err := deserializeResponseBody(semLogContext, resp, resultObj); err != nil {
+		return nil, err
+	}
+
+	return resultObj, nil
+}
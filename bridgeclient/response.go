@@ -2,7 +2,9 @@ package bridgeclient
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-archive/har"
 	"github.com/rs/zerolog/log"
 	"net/http"
 	"strings"
@@ -150,3 +152,29 @@ func ErrorCode(err error) string {
 
 	return ""
 }
+
+// deserializeResponseBody unmarshals a 200 response into resultObj and turns the other statuses into an *ApiResponse error.
+func deserializeResponseBody(semLogContext string, resp *har.Entry, resultObj interface{}) error {
+	if resp == nil || resp.Response == nil || resp.Response.Content == nil || resp.Response.Content.Data == nil {
+		err := errors.New("cannot deserialize null response")
+		log.Error().Err(err).Msg(semLogContext)
+		return NewExecutableServerError(WithErrorMessage(err.Error()))
+	}
+
+	switch resp.Response.Status {
+	case http.StatusOK:
+		if err := json.Unmarshal(resp.Response.Content.Data, resultObj); err != nil {
+			return NewExecutableServerError(WithErrorMessage(err.Error()))
+		}
+
+	default:
+		apiResponse, err := DeserApiResponseFromJson(resp.Response.Content.Data)
+		if err != nil {
+			return NewExecutableServerError(WithErrorMessage(err.Error()))
+		}
+		apiResponse.StatusCode = resp.Response.Status
+		return &apiResponse
+	}
+
+	return nil
+}
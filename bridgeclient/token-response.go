@@ -0,0 +1,156 @@
+package bridgeclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+const (
+	ResponseFieldTokenId      = "token-id"
+	ResponseFieldCreationDate = "creation-date"
+	ResponseFieldStatus       = "status"
+	ResponseFieldProperties   = "properties"
+)
+
+// ResponseExtensions the fields of a bridge response beyond the ones of the response type, with typed accessors.
+type ResponseExtensions map[string]interface{}
+
+func (e ResponseExtensions) Has(n string) bool {
+	_, ok := e[n]
+	return ok
+}
+
+func (e ResponseExtensions) String(n string) (string, bool) {
+	v, ok := e[n]
+	if !ok || v == nil {
+		return "", false
+	}
+
+	switch tv := v.(type) {
+	case string:
+		return tv, true
+	case float64, bool, json.Number:
+		return fmt.Sprint(tv), true
+	}
+
+	return "", false
+}
+
+func (e ResponseExtensions) Bool(n string) (bool, bool) {
+	switch tv := e[n].(type) {
+	case bool:
+		return tv, true
+	case string:
+		b, err := strconv.ParseBool(tv)
+		return b, err == nil
+	}
+
+	return false, false
+}
+
+func (e ResponseExtensions) Int(n string) (int64, bool) {
+	switch tv := e[n].(type) {
+	case float64:
+		if tv == float64(int64(tv)) {
+			return int64(tv), true
+		}
+	case json.Number:
+		i, err := tv.Int64()
+		return i, err == nil
+	case string:
+		i, err := strconv.ParseInt(tv, 10, 64)
+		return i, err == nil
+	}
+
+	return 0, false
+}
+
+func (e ResponseExtensions) Map(n string) (map[string]interface{}, bool) {
+	m, ok := e[n].(map[string]interface{})
+	return m, ok
+}
+
+// Decode decodes the field into target through its json representation.
+func (e ResponseExtensions) Decode(n string, target interface{}) error {
+	v, ok := e[n]
+	if !ok {
+		return fmt.Errorf("response field %s not present", n)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, target)
+}
+
+// TokenResponse the response of the token operations of the bridge. Fields other than token-id and creation-date are kept in the Extensions.
+type TokenResponse struct {
+	Id           string             `yaml:"token-id,omitempty" mapstructure:"token-id,omitempty" json:"token-id,omitempty"`
+	CreationDate string             `yaml:"creation-date,omitempty" mapstructure:"creation-date,omitempty" json:"creation-date,omitempty"`
+	Extensions   ResponseExtensions `yaml:"extensions,omitempty" mapstructure:"extensions,omitempty" json:"-"`
+}
+
+func (r *TokenResponse) Status() string {
+	s, _ := r.Extensions.String(ResponseFieldStatus)
+	return s
+}
+
+func (r *TokenResponse) Properties() map[string]interface{} {
+	m, _ := r.Extensions.Map(ResponseFieldProperties)
+	return m
+}
+
+func (r *TokenResponse) UnmarshalJSON(b []byte) error {
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+
+	*r = TokenResponse{}
+	switch v := m[ResponseFieldTokenId].(type) {
+	case string:
+		r.Id = v
+		delete(m, ResponseFieldTokenId)
+	case float64:
+		// numeric ids of legacy systems are kept with their literal digits, a float64 would lose the large ones.
+		var n struct {
+			Id json.Number `json:"token-id"`
+		}
+		if err := json.Unmarshal(b, &n); err != nil {
+			return err
+		}
+		r.Id = n.Id.String()
+		delete(m, ResponseFieldTokenId)
+	}
+
+	if s, ok := m[ResponseFieldCreationDate].(string); ok {
+		r.CreationDate = s
+		delete(m, ResponseFieldCreationDate)
+	}
+
+	if len(m) > 0 {
+		r.Extensions = m
+	}
+
+	return nil
+}
+
+func (r TokenResponse) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(r.Extensions)+2)
+	for n, v := range r.Extensions {
+		m[n] = v
+	}
+
+	if r.Id != "" {
+		m[ResponseFieldTokenId] = r.Id
+	}
+
+	if r.CreationDate != "" {
+		m[ResponseFieldCreationDate] = r.CreationDate
+	}
+
+	return json.Marshal(m)
+}
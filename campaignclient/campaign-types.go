@@ -67,6 +67,9 @@ type Filters struct {
 	Prodotto string `yaml:"prodotto,omitempty" mapstructure:"prodotto,omitempty" json:"prodotto,omitempty"`
 	Fase     string `yaml:"fase,omitempty" mapstructure:"fase,omitempty" json:"fase,omitempty"`
 	Timing   string `yaml:"-" mapstructure:"-" json:"-"`
+	// AsOf the instant the timing criteria is evaluated at. If zero the current time of Clock is used, the system time if not set.
+	AsOf  time.Time   `yaml:"-" mapstructure:"-" json:"-"`
+	Clock token.Clock `yaml:"-" mapstructure:"-" json:"-"`
 }
 
 type Campaign struct {
@@ -109,7 +112,12 @@ type CampaignInfo struct {
 func (c *CampaignInfo) Accept(criteria *Filters) bool {
 
 	rc := true
-	if rc && !checkTiming(c.Timeline, criteria.Timing) {
+	asOf := criteria.AsOf
+	if asOf.IsZero() {
+		asOf = now(criteria.Clock)
+	}
+
+	if rc && !checkTiming(c.Timeline, criteria.Timing, asOf) {
 		rc = false
 	}
 
@@ -153,26 +161,19 @@ func checkFilter(val string, criteria string) bool {
 	return strings.ToLower(val) == criteria
 }
 
-func checkTiming(val token.Timeline, criteria string) bool {
+func checkTiming(val token.Timeline, criteria string, asOf time.Time) bool {
 	if criteria == "" {
 		return true
 	}
 
-	today := time.Now().Format("20060102")
 	rc := false
 	switch criteria {
 	case "next":
-		if today < val.StartDate {
-			rc = true
-		}
+		rc = val.IsNotStartedYetAt(asOf)
 	case "current":
-		if today >= val.StartDate && today <= val.EndDate {
-			rc = true
-		}
+		rc = val.IsInRangeAt(asOf)
 	case "past":
-		if today > val.EndDate {
-			rc = true
-		}
+		rc = val.IsOverAt(asOf)
 	}
 
 	return rc
@@ -225,10 +226,23 @@ func (c *Campaign) Valid() bool {
 }
 
 func (c *Campaign) IsActive() bool {
-	today := time.Now().Format("20060102")
-	if today >= c.Timeline.StartDate && today <= c.Timeline.EndDate {
-		return true
+	return c.IsActiveAt(time.Now())
+}
+
+// IsActiveWith evaluates the campaign at the current time of the clock.
+func (c *Campaign) IsActiveWith(clock token.Clock) bool {
+	return c.IsActiveAt(now(clock))
+}
+
+// now the current time of the clock, the system time if nil.
+func now(clock token.Clock) time.Time {
+	if clock == nil {
+		return time.Now()
 	}
 
-	return false
+	return clock.Now()
+}
+
+func (c *Campaign) IsActiveAt(asOf time.Time) bool {
+	return c.Timeline.IsInRangeAt(asOf)
 }
@@ -0,0 +1,296 @@
+package campaignclient
+
+import (
+	"errors"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/businessview"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+type PortfolioSortOrder string
+
+const (
+	PortfolioSortByExpiry  PortfolioSortOrder = "expiry"
+	PortfolioSortByState   PortfolioSortOrder = "state"
+	PortfolioSortByTokenId PortfolioSortOrder = "token-id"
+)
+
+type PortfolioToken struct {
+	TokenId          string                  `yaml:"token-id,omitempty" mapstructure:"token-id,omitempty" json:"token-id,omitempty"`
+	CampaignId       string                  `yaml:"campaign-id,omitempty" mapstructure:"campaign-id,omitempty" json:"campaign-id,omitempty"`
+	Role             string                  `yaml:"role,omitempty" mapstructure:"role,omitempty" json:"role,omitempty"`
+	State            string                  `yaml:"state,omitempty" mapstructure:"state,omitempty" json:"state,omitempty"`
+	StateDescription string                  `yaml:"state-description,omitempty" mapstructure:"state-description,omitempty" json:"state-description,omitempty"`
+	Pending          bool                    `yaml:"pending,omitempty" mapstructure:"pending,omitempty" json:"pending,omitempty"`
+	ExpiryTs         string                  `yaml:"expiry-ts,omitempty" mapstructure:"expiry-ts,omitempty" json:"expiry-ts,omitempty"`
+	Expiry           time.Time               `yaml:"expiry,omitempty" mapstructure:"expiry,omitempty" json:"expiry,omitempty"`
+	Active           bool                    `yaml:"active,omitempty" mapstructure:"active,omitempty" json:"active,omitempty"`
+	Properties       []businessview.Property `yaml:"properties,omitempty" mapstructure:"properties,omitempty" json:"properties,omitempty"`
+}
+
+// ExpiresWithin the token is not expired as of asOf and expires in less than d.
+func (pt *PortfolioToken) ExpiresWithin(asOf time.Time, d time.Duration) bool {
+	if pt.Expiry.IsZero() || !asOf.Before(pt.Expiry) {
+		return false
+	}
+
+	return pt.Expiry.Sub(asOf) <= d
+}
+
+type PortfolioCampaign struct {
+	CampaignId string           `yaml:"campaign-id,omitempty" mapstructure:"campaign-id,omitempty" json:"campaign-id,omitempty"`
+	Title      string           `yaml:"title,omitempty" mapstructure:"title,omitempty" json:"title,omitempty"`
+	Info       *CampaignInfo    `yaml:"info,omitempty" mapstructure:"info,omitempty" json:"info,omitempty"`
+	Active     bool             `yaml:"active,omitempty" mapstructure:"active,omitempty" json:"active,omitempty"`
+	Tokens     []PortfolioToken `yaml:"tokens,omitempty" mapstructure:"tokens,omitempty" json:"tokens,omitempty"`
+}
+
+// Portfolio the tokens of an actor across campaigns, grouped by campaign.
+type Portfolio struct {
+	ActorId   string              `yaml:"actor-id,omitempty" mapstructure:"actor-id,omitempty" json:"actor-id,omitempty"`
+	AsOf      time.Time           `yaml:"as-of,omitempty" mapstructure:"as-of,omitempty" json:"as-of,omitempty"`
+	Campaigns []PortfolioCampaign `yaml:"campaigns,omitempty" mapstructure:"campaigns,omitempty" json:"campaigns,omitempty"`
+}
+
+// Tokens the tokens of all the campaigns.
+func (p *Portfolio) Tokens() []PortfolioToken {
+	var tks []PortfolioToken
+	for _, c := range p.Campaigns {
+		tks = append(tks, c.Tokens...)
+	}
+
+	return tks
+}
+
+func (p *Portfolio) NumberOfTokens() int {
+	n := 0
+	for _, c := range p.Campaigns {
+		n += len(c.Tokens)
+	}
+
+	return n
+}
+
+type PortfolioOptions struct {
+	AsOf           time.Time
+	Clock          token.Clock
+	ActiveOnly     bool
+	ExpiringWithin time.Duration
+	SortOrder      PortfolioSortOrder
+}
+
+type PortfolioOption func(opts *PortfolioOptions)
+
+func PortfolioWithAsOf(asOf time.Time) PortfolioOption {
+	return func(opts *PortfolioOptions) {
+		opts.AsOf = asOf
+	}
+}
+
+// PortfolioWithClock the clock giving the as-of instant when not set explicitly. The system clock by default.
+func PortfolioWithClock(c token.Clock) PortfolioOption {
+	return func(opts *PortfolioOptions) {
+		opts.Clock = c
+	}
+}
+
+// PortfolioWithActiveOnly keeps only the active tokens of active campaigns: tokens not expired and not in a final state.
+func PortfolioWithActiveOnly() PortfolioOption {
+	return func(opts *PortfolioOptions) {
+		opts.ActiveOnly = true
+	}
+}
+
+// PortfolioWithExpiringWithin keeps only the tokens expiring in the next n days.
+func PortfolioWithExpiringWithin(n int) PortfolioOption {
+	return func(opts *PortfolioOptions) {
+		opts.ExpiringWithin = time.Duration(n) * 24 * time.Hour
+	}
+}
+
+func PortfolioWithSortOrder(o PortfolioSortOrder) PortfolioOption {
+	return func(opts *PortfolioOptions) {
+		opts.SortOrder = o
+	}
+}
+
+// BuildPortfolio combines the actor view (possibly a full view, so that token details are available) with the campaigns. Tokens of campaigns
+// missing in campaigns are kept without campaign info and their state is not evaluated. Campaigns are ordered by id, tokens according to
+// the sort order (expiry by default, tokens without expiry last).
+func BuildPortfolio(actor *businessview.Actor, campaigns []Campaign, opts ...PortfolioOption) *Portfolio {
+	const semLogContext = "campaign-client::build-portfolio"
+
+	pOpts := PortfolioOptions{SortOrder: PortfolioSortByExpiry}
+	for _, o := range opts {
+		o(&pOpts)
+	}
+
+	if pOpts.AsOf.IsZero() {
+		pOpts.AsOf = now(pOpts.Clock)
+	}
+
+	cmps := make(map[string]*Campaign)
+	for i := range campaigns {
+		cmps[WellFormCampaignId(campaigns[i].Id)] = &campaigns[i]
+	}
+
+	p := Portfolio{ActorId: actor.ActorId, AsOf: pOpts.AsOf}
+	groups := make(map[string]*PortfolioCampaign)
+	var ids []string
+	for _, ber := range actor.Bearers {
+		cid := WellFormCampaignId(ber.ContextId)
+		cmp := cmps[cid]
+		pc, ok := groups[cid]
+		if !ok {
+			pc = &PortfolioCampaign{CampaignId: cid, Active: true}
+			if cmp != nil {
+				info := cmp.Info()
+				pc.Info = &info
+				pc.Title = info.Title
+				pc.Active = info.Timeline.IsInRangeAt(pOpts.AsOf)
+			} else {
+				log.Warn().Str("campaign-id", cid).Msg(semLogContext + " campaign not available")
+			}
+			groups[cid] = pc
+			ids = append(ids, cid)
+		}
+
+		for _, tr := range ber.TokenRefs {
+			pt := newPortfolioToken(cid, tr, cmp, pOpts.AsOf)
+			pt.Active = pt.Active && pc.Active
+			if pOpts.ActiveOnly && !pt.Active {
+				continue
+			}
+
+			if pOpts.ExpiringWithin > 0 && !pt.ExpiresWithin(pOpts.AsOf, pOpts.ExpiringWithin) {
+				continue
+			}
+
+			pc.Tokens = append(pc.Tokens, pt)
+		}
+	}
+
+	sort.Strings(ids)
+	for _, cid := range ids {
+		pc := groups[cid]
+		if len(pc.Tokens) == 0 && (pOpts.ActiveOnly || pOpts.ExpiringWithin > 0) {
+			continue
+		}
+
+		sortPortfolioTokens(pc.Tokens, pOpts.SortOrder)
+		p.Campaigns = append(p.Campaigns, *pc)
+	}
+
+	return &p
+}
+
+// newPortfolioToken a token is active if not expired as of asOf and, when the campaign is known, not in a final state.
+func newPortfolioToken(campaignId string, tr businessview.TokenRef, cmp *Campaign, asOf time.Time) PortfolioToken {
+	const semLogContext = "campaign-client::portfolio-token"
+
+	pt := PortfolioToken{
+		TokenId:    tr.Token.Id,
+		CampaignId: campaignId,
+		Role:       tr.Role,
+		ExpiryTs:   tr.Token.ExpiryTs,
+		Properties: tr.Token.Properties,
+		Active:     true,
+	}
+
+	if n := len(tr.Token.Events); n > 0 {
+		st := tr.Token.Events[n-1].State
+		pt.State = st.Code
+		pt.StateDescription = st.Description
+		pt.Pending = st.Pending
+	}
+
+	if cmp != nil && pt.State != "" {
+		sd, err := cmp.StateMachine.FindStateDefinition(pt.State)
+		if err != nil {
+			log.Warn().Err(err).Str("token-id", pt.TokenId).Str("state", pt.State).Msg(semLogContext + " unknown token state")
+		} else if sd.IsFinal() {
+			pt.Active = false
+		}
+	}
+
+	if pt.ExpiryTs != "" {
+		mode := ""
+		loc := time.Local
+		if cmp != nil {
+			mode = cmp.Timeline.ExpirationMode
+			if l, err := cmp.Timeline.Loc(); err != nil {
+				log.Warn().Err(err).Str("campaign-id", campaignId).Str("location", cmp.Timeline.Location).Msg(semLogContext + " invalid timeline location")
+			} else {
+				loc = l
+			}
+		}
+
+		tok := token.Token{Id: pt.TokenId, Events: []token.Event{{ExpiryTs: pt.ExpiryTs}}}
+		exp, ok, err := tok.ExpiryTime(mode, loc)
+		if err != nil {
+			log.Warn().Err(err).Str("token-id", pt.TokenId).Str("expiry-ts", pt.ExpiryTs).Msg(semLogContext + " invalid expiry")
+		} else if ok {
+			pt.Expiry = exp
+			pt.Active = pt.Active && asOf.Before(exp)
+		}
+	}
+
+	return pt
+}
+
+func sortPortfolioTokens(tks []PortfolioToken, order PortfolioSortOrder) {
+	sort.SliceStable(tks, func(i, j int) bool {
+		a, b := tks[i], tks[j]
+		switch order {
+		case PortfolioSortByState:
+			if a.State != b.State {
+				return a.State < b.State
+			}
+		case PortfolioSortByTokenId:
+		default:
+			if !a.Expiry.Equal(b.Expiry) {
+				if a.Expiry.IsZero() || b.Expiry.IsZero() {
+					return b.Expiry.IsZero()
+				}
+				return a.Expiry.Before(b.Expiry)
+			}
+		}
+
+		return a.TokenId < b.TokenId
+	})
+}
+
+// Portfolio loads the campaigns the actor has tokens in and builds the portfolio. Campaigns not found are logged and left without info,
+// any other error is returned.
+func (c *Client) Portfolio(reqCtx ApiRequestContext, actor *businessview.Actor, opts ...PortfolioOption) (*Portfolio, error) {
+	const semLogContext = "campaign-client::portfolio"
+
+	var cmps []Campaign
+	loaded := make(map[string]struct{})
+	for _, ber := range actor.Bearers {
+		cid := WellFormCampaignId(ber.ContextId)
+		if _, ok := loaded[cid]; ok {
+			continue
+		}
+		loaded[cid] = struct{}{}
+
+		cmp, err := c.GetCampaignById(reqCtx, cid)
+		if err != nil {
+			var apiErr *ApiResponse
+			if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+				log.Warn().Err(err).Str("campaign-id", cid).Msg(semLogContext + " campaign not found")
+				continue
+			}
+
+			log.Error().Err(err).Str("campaign-id", cid).Msg(semLogContext + " campaign not loaded")
+			return nil, err
+		}
+
+		cmps = append(cmps, *cmp)
+	}
+
+	return BuildPortfolio(actor, cmps, opts...), nil
+}
@@ -0,0 +1,131 @@
+package campaignclient_test
+
+import (
+	"encoding/json"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/campaignclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/businessview"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPortfolio(t *testing.T) {
+
+	tok := func(id, expiry, state string) businessview.Token {
+		return businessview.Token{Id: id, ExpiryTs: expiry, Events: []businessview.Event{{State: businessview.EventState{Code: state}}}}
+	}
+
+	actor := businessview.Actor{
+		ActorId: "MPRMLS62S21G337J",
+		Bearers: []businessview.Bearer{
+			{ContextId: "bpmgm1", TokenRefs: []businessview.TokenRef{
+				{Token: tok("TOK2", "20230520", "generato"), Role: "primary"},
+				{Token: tok("TOK1", "20230505", "generato"), Role: "primary"},
+				{Token: tok("TOK3", "20230410", "scaduto"), Role: "secondary"},
+				{Token: tok("TOK5", "20230530", "bruciato"), Role: "primary"},
+			}},
+			{ContextId: "OLD1", TokenRefs: []businessview.TokenRef{{Token: tok("TOK4", "", "generato")}}},
+		},
+	}
+
+	sm := token.StateMachine{States: []token.StateDefinition{
+		{Code: "generato", StateType: token.StateStd},
+		{Code: "scaduto", StateType: token.StateExpired},
+		{Code: "bruciato", StateType: token.StateFinal},
+	}}
+
+	campaigns := []campaignclient.Campaign{
+		{TokenContext: token.TokenContext{Id: "BPMGM1", StateMachine: sm, Timeline: token.Timeline{StartDate: "20230101", EndDate: "20231231", Location: "UTC"}}, Title: "Porta un amico"},
+		{TokenContext: token.TokenContext{Id: "OLD1", StateMachine: sm, Timeline: token.Timeline{StartDate: "20220101", EndDate: "20221231", Location: "UTC"}}, Title: "Vecchia"},
+	}
+
+	asOf := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	p := campaignclient.BuildPortfolio(&actor, campaigns, campaignclient.PortfolioWithAsOf(asOf))
+	require.Len(t, p.Campaigns, 2)
+	require.Equal(t, "Porta un amico", p.Campaigns[0].Title)
+	require.Equal(t, []string{"TOK3", "TOK1", "TOK2", "TOK5"}, tokenIds(p.Campaigns[0].Tokens))
+	require.Equal(t, time.Date(2023, 5, 6, 0, 0, 0, 0, time.UTC), p.Campaigns[0].Tokens[1].Expiry)
+	require.False(t, p.Campaigns[0].Tokens[3].Active)
+	require.False(t, p.Campaigns[1].Active)
+
+	p = campaignclient.BuildPortfolio(&actor, campaigns, campaignclient.PortfolioWithAsOf(asOf), campaignclient.PortfolioWithActiveOnly())
+	require.Len(t, p.Campaigns, 1)
+	require.Equal(t, []string{"TOK1", "TOK2"}, tokenIds(p.Tokens()))
+
+	p = campaignclient.BuildPortfolio(&actor, campaigns, campaignclient.PortfolioWithAsOf(asOf), campaignclient.PortfolioWithExpiringWithin(7))
+	require.Equal(t, []string{"TOK1"}, tokenIds(p.Tokens()))
+
+	// without an explicit as-of instant the clock gives the time.
+	clock := token.NewFakeClock(asOf)
+	p = campaignclient.BuildPortfolio(&actor, campaigns, campaignclient.PortfolioWithClock(clock), campaignclient.PortfolioWithActiveOnly())
+	require.Equal(t, asOf, p.AsOf)
+	require.Equal(t, []string{"TOK1", "TOK2"}, tokenIds(p.Tokens()))
+
+	clock.Set(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	p = campaignclient.BuildPortfolio(&actor, campaigns, campaignclient.PortfolioWithClock(clock), campaignclient.PortfolioWithActiveOnly())
+	require.Empty(t, p.Tokens())
+	require.False(t, campaigns[0].IsActiveWith(clock))
+
+	info := campaignclient.CampaignInfo{Id: "BPMGM1", Timeline: campaigns[0].Timeline}
+	require.False(t, info.Accept(&campaignclient.Filters{Timing: "current", Clock: clock}))
+	clock.Set(asOf)
+	require.True(t, campaigns[0].IsActiveWith(clock))
+	require.True(t, info.Accept(&campaignclient.Filters{Timing: "current", Clock: clock}))
+
+	// expiries of campaigns in timestamp mode.
+	tsCampaigns := []campaignclient.Campaign{
+		{TokenContext: token.TokenContext{Id: "BPMGM1", StateMachine: sm, Timeline: token.Timeline{StartDate: "20230101", EndDate: "20231231", Location: "UTC", ExpirationMode: token.ExpirationModeTimestamp}}},
+	}
+	tsActor := businessview.Actor{Bearers: []businessview.Bearer{{ContextId: "BPMGM1", TokenRefs: []businessview.TokenRef{{Token: tok("TOK1", "2023-05-01T09:00:00Z", "generato")}}}}}
+	p = campaignclient.BuildPortfolio(&tsActor, tsCampaigns, campaignclient.PortfolioWithAsOf(asOf))
+	require.Equal(t, time.Date(2023, 5, 1, 9, 0, 0, 0, time.UTC), p.Tokens()[0].Expiry)
+	require.False(t, p.Tokens()[0].Active)
+}
+
+func TestClientPortfolio(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/BPMGM1"):
+			_ = json.NewEncoder(w).Encode(&campaignclient.Campaign{TokenContext: token.TokenContext{Id: "BPMGM1"}, Title: "Porta un amico"})
+		case strings.HasSuffix(r.URL.Path, "/DOWN1"):
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"error-code": "unavailable", "text": "service unavailable"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error-code": "campaign-not-found", "text": "campaign not found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+	cli, err := campaignclient.NewCampaignApiClient(&campaignclient.Config{Host: campaignclient.HostInfo{Scheme: "http", HostName: u.Hostname(), Port: port}})
+	require.NoError(t, err)
+
+	actor := businessview.Actor{Bearers: []businessview.Bearer{{ContextId: "BPMGM1"}, {ContextId: "GONE1"}}}
+	p, err := cli.Portfolio(campaignclient.NewApiRequestContext(), &actor)
+	require.NoError(t, err)
+	require.Len(t, p.Campaigns, 2)
+	require.Equal(t, "Porta un amico", p.Campaigns[0].Title)
+	require.Nil(t, p.Campaigns[1].Info)
+
+	actor.Bearers = append(actor.Bearers, businessview.Bearer{ContextId: "DOWN1"})
+	_, err = cli.Portfolio(campaignclient.NewApiRequestContext(), &actor)
+	require.Error(t, err)
+}
+
+func tokenIds(tks []campaignclient.PortfolioToken) []string {
+	var ids []string
+	for _, t := range tks {
+		ids = append(ids, t.TokenId)
+	}
+	return ids
+}
@@ -0,0 +1,68 @@
+package jsonpathutil
+
+import (
+	"github.com/PaesslerAG/jsonpath"
+	"regexp"
+	"strings"
+)
+
+var singleReferenceRegexp = regexp.MustCompile(`^\{(\$[^{}]*)\}$`)
+
+// SingleReference tells if the template is made of a single json path reference (i.e. {$.data.customer-id}) and returns the path.
+func SingleReference(tmpl string) (string, bool) {
+	matches := singleReferenceRegexp.FindStringSubmatch(tmpl)
+	if matches == nil {
+		return "", false
+	}
+
+	return matches[1], true
+}
+
+// Get evaluates the path, dashed names allowed, against v. A missing key is not an error and yields a nil value.
+func Get(path string, v interface{}) (interface{}, error) {
+	r, err := jsonpath.Get(BracketPath(path), v)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "unknown key") {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return r, nil
+}
+
+var identifierRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// BracketPath rewrites the dotted segments that are not identifiers (i.e. $.customer-id) in bracket notation ($["customer-id"]).
+func BracketPath(p string) string {
+	if !strings.HasPrefix(p, "$.") || strings.ContainsAny(p, "[]\"") {
+		return p
+	}
+
+	var sb strings.Builder
+	sb.WriteString("$")
+	for _, seg := range strings.Split(strings.TrimPrefix(p, "$."), ".") {
+		if identifierRegexp.MatchString(seg) {
+			sb.WriteString("." + seg)
+		} else {
+			sb.WriteString(`["` + seg + `"]`)
+		}
+	}
+
+	return sb.String()
+}
+
+// SetField sets the field name, dot separated for nested objects, creating the intermediate objects as needed.
+func SetField(obj map[string]interface{}, name string, v interface{}) {
+	path := strings.Split(name, ".")
+	for _, p := range path[:len(path)-1] {
+		nested, ok := obj[p].(map[string]interface{})
+		if !ok {
+			nested = make(map[string]interface{})
+			obj[p] = nested
+		}
+		obj = nested
+	}
+
+	obj[path[len(path)-1]] = v
+}
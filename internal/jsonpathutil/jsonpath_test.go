@@ -0,0 +1,33 @@
+package jsonpathutil_test
+
+import (
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/internal/jsonpathutil"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestJsonPath(t *testing.T) {
+
+	p, ok := jsonpathutil.SingleReference("{$.data.customer-id}")
+	require.True(t, ok)
+	require.Equal(t, "$.data.customer-id", p)
+
+	_, ok = jsonpathutil.SingleReference("id: {$.data.customer-id}")
+	require.False(t, ok)
+
+	require.Equal(t, `$.data["customer-id"]`, jsonpathutil.BracketPath(p))
+	require.Equal(t, `$["data"]`, jsonpathutil.BracketPath(`$["data"]`))
+
+	obj := map[string]interface{}{}
+	jsonpathutil.SetField(obj, "data.customer-id", "C1")
+	jsonpathutil.SetField(obj, "data.amount", 10.0)
+	require.Equal(t, map[string]interface{}{"data": map[string]interface{}{"customer-id": "C1", "amount": 10.0}}, obj)
+
+	v, err := jsonpathutil.Get(p, obj)
+	require.NoError(t, err)
+	require.Equal(t, "C1", v)
+
+	v, err = jsonpathutil.Get("$.data.missing", obj)
+	require.NoError(t, err)
+	require.Nil(t, v)
+}
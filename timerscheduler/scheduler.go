@@ -0,0 +1,380 @@
+package timerscheduler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-common/util/expression"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-client/restclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/actionsclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/rs/zerolog/log"
+	"hash/fnv"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	semLogContextBase = "timer-scheduler"
+
+	DefaultPollInterval = time.Minute
+
+	TimerActionScope = "timer"
+)
+
+// TimersStore the timer operations used by the scheduler. tokensclient.Client implements it.
+type TimersStore interface {
+	QueryTimers(reqCtx tokensclient.ApiRequestContext, ctxId string, tokId string, statuses ...string) ([]token.Timer, error)
+	UpdateTimerStatus(reqCtx tokensclient.ApiRequestContext, ctxId string, tokId string, timerId string, status string) (*token.Timer, error)
+}
+
+// ActionsCaller invokes the actions of the timer definitions. actionsclient.LinkedService implements it.
+type ActionsCaller interface {
+	CallAction(actionId string, expressionCtx *expression.Context, body map[string]interface{}, opts ...restclient.Option) (map[string]interface{}, error)
+}
+
+type Config struct {
+	// Contexts the token contexts whose timers are loaded at every poll.
+	Contexts     []string      `yaml:"contexts,omitempty" mapstructure:"contexts,omitempty" json:"contexts,omitempty"`
+	PollInterval time.Duration `yaml:"poll-interval,omitempty" mapstructure:"poll-interval,omitempty" json:"poll-interval,omitempty"`
+	// Shards and ShardIndex split the timers among replicas: a replica handles only the timers whose id hashes to its index.
+	Shards     int `yaml:"shards,omitempty" mapstructure:"shards,omitempty" json:"shards,omitempty"`
+	ShardIndex int `yaml:"shard-index,omitempty" mapstructure:"shard-index,omitempty" json:"shard-index,omitempty"`
+}
+
+// Scheduler fires the actions of the active timers when they expire. The scheduler keeps no state of its own other than the timers waiting
+// in memory: the status of the timer on the server is the source of truth, so after a restart the active timers are simply loaded again and
+// the ones expired in the meanwhile fire at once.
+type Scheduler struct {
+	cfg     Config
+	store   TimersStore
+	actions ActionsCaller
+	clock   token.Clock
+
+	mu      sync.Mutex
+	stopped bool
+	pending map[string]token.ClockTimer
+	running map[string]struct{}
+	quit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+type Option func(s *Scheduler)
+
+// WithClock the clock timers expiries are compared to and armed timers fire on. The system clock by default.
+func WithClock(c token.Clock) Option {
+	return func(s *Scheduler) {
+		if c != nil {
+			s.clock = c
+		}
+	}
+}
+
+func NewScheduler(cfg Config, store TimersStore, actions ActionsCaller, opts ...Option) (*Scheduler, error) {
+	const semLogContext = semLogContextBase + "::new"
+
+	if store == nil || actions == nil {
+		err := errors.New("timer scheduler requires timers store and actions")
+		log.Error().Err(err).Msg(semLogContext)
+		return nil, err
+	}
+
+	if cfg.Shards > 0 && (cfg.ShardIndex < 0 || cfg.ShardIndex >= cfg.Shards) {
+		err := fmt.Errorf("invalid shard index %d of %d", cfg.ShardIndex, cfg.Shards)
+		log.Error().Err(err).Msg(semLogContext)
+		return nil, err
+	}
+
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultPollInterval
+	}
+
+	s := &Scheduler{
+		cfg:     cfg,
+		store:   store,
+		actions: actions,
+		clock:   token.SystemClock,
+		pending: make(map[string]token.ClockTimer),
+		running: make(map[string]struct{}),
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s, nil
+}
+
+// NewSchedulerWithLinkedServices wires the scheduler to the tokens and actions linked services.
+func NewSchedulerWithLinkedServices(cfg Config, tokens *tokensclient.LinkedService, actions *actionsclient.LinkedService, opts ...restclient.Option) (*Scheduler, error) {
+	cli, err := tokens.NewClient(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewScheduler(cfg, cli, actions)
+}
+
+// IsOwned tells if the timer belongs to the shard of the scheduler.
+func (s *Scheduler) IsOwned(tm *token.Timer) bool {
+	if s.cfg.Shards <= 1 {
+		return true
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(token.WellFormTimerId(tm.Id)))
+	return int(h.Sum32()%uint32(s.cfg.Shards)) == s.cfg.ShardIndex
+}
+
+func (s *Scheduler) Start() {
+	const semLogContext = semLogContextBase + "::start"
+
+	s.mu.Lock()
+	if s.quit != nil {
+		s.mu.Unlock()
+		log.Warn().Msg(semLogContext + " scheduler already started")
+		return
+	}
+	s.quit = make(chan struct{})
+	s.stopped = false
+	quit := s.quit
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.cfg.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.Poll(tokensclient.NewApiRequestContext(tokensclient.ApiRequestWithAutoRequestId())); err != nil {
+				log.Error().Err(err).Msg(semLogContext + " poll error")
+			}
+
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends polling and drops the timers waiting in memory. Timers being fired are waited for. Timers scheduled after the stop are ignored.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	quit := s.quit
+	s.quit = nil
+	s.stopped = true
+	for id, t := range s.pending {
+		t.Stop()
+		delete(s.pending, id)
+	}
+	s.mu.Unlock()
+
+	if quit != nil {
+		close(quit)
+	}
+	s.wg.Wait()
+}
+
+// Poll loads the active timers of the configured contexts and schedules them.
+func (s *Scheduler) Poll(reqCtx tokensclient.ApiRequestContext) error {
+	var errs []error
+	for _, ctxId := range s.cfg.Contexts {
+		tms, err := s.store.QueryTimers(reqCtx, ctxId, "", token.StatusTimerActive)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		s.Schedule(tms...)
+	}
+
+	return errors.Join(errs...)
+}
+
+// ScheduleToken schedules the active timers of the current state of the token.
+func (s *Scheduler) ScheduleToken(ctxId string, tok *token.Token) {
+	s.Schedule(tok.FindActiveTimers(ctxId, tok.Id)...)
+}
+
+// Schedule fires the expired timers and arms the ones expiring before the next poll. Timers of other shards, already known or
+// not active are ignored.
+func (s *Scheduler) Schedule(tms ...token.Timer) {
+	const semLogContext = semLogContextBase + "::schedule"
+
+	now := s.clock.Now()
+	for i := range tms {
+		tm := tms[i]
+		if tm.Outdated || tm.TimerDefinition == nil || (tm.Status != "" && tm.Status != token.StatusTimerActive) || !s.IsOwned(&tm) {
+			continue
+		}
+
+		exp, err := tm.ExpiresTime(nil)
+		if err != nil {
+			log.Error().Err(err).Str("timer-id", tm.Id).Str("expires", tm.Expires).Msg(semLogContext + " invalid expiration")
+			continue
+		}
+
+		d := exp.Sub(now)
+		if d > s.cfg.PollInterval {
+			continue
+		}
+
+		id := token.WellFormTimerId(tm.Id)
+		s.mu.Lock()
+		if s.stopped {
+			s.mu.Unlock()
+			log.Info().Str("timer-id", tm.Id).Msg(semLogContext + " scheduler stopped")
+			return
+		}
+
+		_, isPending := s.pending[id]
+		_, isRunning := s.running[id]
+		if isPending || isRunning {
+			s.mu.Unlock()
+			continue
+		}
+
+		if d <= 0 {
+			s.running[id] = struct{}{}
+			s.wg.Add(1)
+			s.mu.Unlock()
+			go s.run(tm)
+			continue
+		}
+
+		s.pending[id] = token.AfterFunc(s.clock, d, func() {
+			s.mu.Lock()
+			if _, ok := s.pending[id]; !ok {
+				s.mu.Unlock()
+				return
+			}
+			delete(s.pending, id)
+			s.running[id] = struct{}{}
+			s.wg.Add(1)
+			s.mu.Unlock()
+			s.run(tm)
+		})
+		s.mu.Unlock()
+	}
+}
+
+func (s *Scheduler) run(tm token.Timer) {
+	const semLogContext = semLogContextBase + "::run"
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, token.WellFormTimerId(tm.Id))
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+
+	status := token.StatusTimerProcessed
+	if err := s.Fire(&tm); err != nil {
+		log.Error().Err(err).Str("timer-id", tm.Id).Msg(semLogContext + " timer failed")
+		status = token.StatusTimerFailed
+	}
+
+	reqCtx := tokensclient.NewApiRequestContext(tokensclient.ApiRequestWithAutoRequestId())
+	if _, err := s.store.UpdateTimerStatus(reqCtx, tm.CtxId, tm.TokenId, tm.Id, status); err != nil {
+		log.Error().Err(err).Str("timer-id", tm.Id).Str("status", status).Msg(semLogContext + " timer status not updated")
+	}
+}
+
+// Fire evaluates the preconditions of the timer and, if all of them are satisfied, calls its actions. A precondition not satisfied (see
+// IsPreconditionNotSatisfied) leaves the timer processed; any other error, transport and server errors included, fails the timer.
+func (s *Scheduler) Fire(tm *token.Timer) error {
+	const semLogContext = semLogContextBase + "::fire"
+
+	eCtx, err := newTimerExpressionContext(tm)
+	if err != nil {
+		return err
+	}
+
+	body := map[string]interface{}{
+		"ctx-id":   tm.CtxId,
+		"token-id": tm.TokenId,
+		"timer-id": tm.Id,
+		"expires":  tm.Expires,
+	}
+
+	for _, pc := range tm.TimerDefinition.Preconditions {
+		if !pc.IsInScope(TimerActionScope) {
+			continue
+		}
+
+		_, err = s.call(pc, eCtx, body)
+		if err != nil {
+			if IsPreconditionNotSatisfied(err) {
+				log.Info().Err(err).Str("timer-id", tm.Id).Str("action-id", pc.ActionId).Msg(semLogContext + " precondition not satisfied")
+				return nil
+			}
+
+			return err
+		}
+	}
+
+	for _, a := range tm.TimerDefinition.Actions {
+		if !a.IsInScope(TimerActionScope) {
+			continue
+		}
+
+		m, err := s.call(a, eCtx, body)
+		if err != nil {
+			return err
+		}
+
+		for n, v := range m {
+			body[n] = v
+		}
+	}
+
+	return nil
+}
+
+// IsPreconditionNotSatisfied tells if the precondition action answered negatively: a 412 Precondition Failed or its success predicates
+// not met (422 with the actionsclient.SuccessConditionFailedErrorCode error code).
+func IsPreconditionNotSatisfied(err error) bool {
+	var ar *actionsclient.ActionResponse
+	if !errors.As(err, &ar) {
+		return false
+	}
+
+	switch ar.StatusCode {
+	case http.StatusPreconditionFailed:
+		return true
+	case http.StatusUnprocessableEntity:
+		return ar.ErrCode == actionsclient.SuccessConditionFailedErrorCode
+	}
+
+	return false
+}
+
+func (s *Scheduler) call(ad token.ActionDefinition, eCtx *expression.Context, body map[string]interface{}) (map[string]interface{}, error) {
+	props, err := token.EvalProperties(eCtx, ad.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	actionBody := make(map[string]interface{}, len(body)+len(props))
+	for n, v := range body {
+		actionBody[n] = v
+	}
+
+	for n, v := range props {
+		actionBody[n] = v
+	}
+
+	return s.actions.CallAction(ad.ActionId, eCtx, actionBody)
+}
+
+func newTimerExpressionContext(tm *token.Timer) (*expression.Context, error) {
+	return expression.NewContext(expression.WithMapInput(map[string]interface{}{
+		"ctx-id":   tm.CtxId,
+		"token-id": tm.TokenId,
+		"timer-id": tm.Id,
+		"expires":  tm.Expires,
+	}))
+}
@@ -0,0 +1,137 @@
+package timerscheduler_test
+
+import (
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-common/util/expression"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-client/restclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/actionsclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/timerscheduler"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memTimersStore struct {
+	mu     sync.Mutex
+	timers []token.Timer
+}
+
+func (s *memTimersStore) QueryTimers(reqCtx tokensclient.ApiRequestContext, ctxId string, tokId string, statuses ...string) ([]token.Timer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var tms []token.Timer
+	for _, tm := range s.timers {
+		if tm.Status == token.StatusTimerActive {
+			tms = append(tms, tm)
+		}
+	}
+	return tms, nil
+}
+
+func (s *memTimersStore) UpdateTimerStatus(reqCtx tokensclient.ApiRequestContext, ctxId string, tokId string, timerId string, status string) (*token.Timer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.timers {
+		if s.timers[i].Id == timerId {
+			s.timers[i].Status = status
+			tm := s.timers[i]
+			return &tm, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memTimersStore) status(id string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tm := range s.timers {
+		if tm.Id == id {
+			return tm.Status
+		}
+	}
+	return ""
+}
+
+type recordingActions struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (a *recordingActions) CallAction(actionId string, expressionCtx *expression.Context, body map[string]interface{}, opts ...restclient.Option) (map[string]interface{}, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.calls = append(a.calls, actionId+":"+body["timer-id"].(string))
+	switch actionId {
+	case "check-ko":
+		return nil, &actionsclient.ActionResponse{StatusCode: http.StatusUnprocessableEntity, ErrCode: actionsclient.SuccessConditionFailedErrorCode}
+	case "check-down":
+		return nil, &actionsclient.ActionResponse{StatusCode: http.StatusServiceUnavailable}
+	}
+	return nil, nil
+}
+
+func TestScheduler(t *testing.T) {
+
+	clk := token.NewFakeClock(time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC))
+
+	def := func(precondition string) *token.TimerDefinition {
+		return &token.TimerDefinition{
+			Preconditions: []token.ActionDefinition{{ActionId: precondition}},
+			Actions:       []token.ActionDefinition{{ActionId: "notify", Properties: map[string]interface{}{"tok": "{$.token-id}"}}},
+		}
+	}
+
+	expired := clk.Now().Add(-time.Minute).Format(time.RFC3339)
+	store := &memTimersStore{timers: []token.Timer{
+		{Id: "TM1", CtxId: "CTX", TokenId: "TOK1", Status: token.StatusTimerActive, Expires: expired, TimerDefinition: def("check-ok")},
+		{Id: "TM2", CtxId: "CTX", TokenId: "TOK2", Status: token.StatusTimerActive, Expires: expired, TimerDefinition: def("check-ko")},
+		{Id: "TM3", CtxId: "CTX", TokenId: "TOK3", Status: token.StatusTimerActive, Expires: clk.Now().Add(time.Hour).Format(time.RFC3339), TimerDefinition: def("check-ok")},
+		{Id: "TM4", CtxId: "CTX", TokenId: "TOK4", Status: token.StatusTimerActive, Expires: expired, TimerDefinition: def("check-down")},
+	}}
+
+	acts := &recordingActions{}
+	s, err := timerscheduler.NewScheduler(timerscheduler.Config{Contexts: []string{"CTX"}}, store, acts, timerscheduler.WithClock(clk))
+	require.NoError(t, err)
+
+	require.NoError(t, s.Poll(tokensclient.NewApiRequestContext()))
+	s.Stop()
+
+	require.Equal(t, token.StatusTimerProcessed, store.status("TM1"))
+	require.Equal(t, token.StatusTimerProcessed, store.status("TM2"))
+	require.Equal(t, token.StatusTimerActive, store.status("TM3"))
+	require.Equal(t, token.StatusTimerFailed, store.status("TM4"))
+	require.ElementsMatch(t, []string{"check-ok:TM1", "notify:TM1", "check-ko:TM2", "check-down:TM4"}, acts.calls)
+
+	// after the stop nothing is scheduled any more.
+	s.Schedule(token.Timer{Id: "TM5", CtxId: "CTX", TokenId: "TOK5", Status: token.StatusTimerActive, Expires: expired, TimerDefinition: def("check-ok")})
+	s.Stop()
+	require.Len(t, acts.calls, 4)
+
+	// armed timers fire on the time of the clock, not on the wall clock.
+	s, err = timerscheduler.NewScheduler(timerscheduler.Config{Contexts: []string{"CTX"}}, store, acts, timerscheduler.WithClock(clk))
+	require.NoError(t, err)
+	store.timers = append(store.timers, token.Timer{Id: "TM6", CtxId: "CTX", TokenId: "TOK6", Status: token.StatusTimerActive, Expires: clk.Now().Add(50 * time.Millisecond).Format(time.RFC3339Nano), TimerDefinition: def("check-ok")})
+	s.Schedule(store.timers[len(store.timers)-1])
+	time.Sleep(150 * time.Millisecond)
+	require.Equal(t, token.StatusTimerActive, store.status("TM6"))
+
+	clk.Advance(50 * time.Millisecond)
+	require.Eventually(t, func() bool { return store.status("TM6") == token.StatusTimerProcessed }, time.Second, 10*time.Millisecond)
+	s.Stop()
+	require.Equal(t, token.StatusTimerActive, store.status("TM3"))
+
+	require.False(t, timerscheduler.IsPreconditionNotSatisfied(&actionsclient.ActionResponse{StatusCode: http.StatusUnprocessableEntity}))
+	require.True(t, timerscheduler.IsPreconditionNotSatisfied(&actionsclient.ActionResponse{StatusCode: http.StatusPreconditionFailed}))
+
+	_, err = timerscheduler.NewScheduler(timerscheduler.Config{Shards: 2, ShardIndex: 2}, store, acts)
+	require.Error(t, err)
+
+	s0, _ := timerscheduler.NewScheduler(timerscheduler.Config{Shards: 2, ShardIndex: 0}, store, acts)
+	s1, _ := timerscheduler.NewScheduler(timerscheduler.Config{Shards: 2, ShardIndex: 1}, store, acts)
+	for _, tm := range store.timers {
+		require.NotEqual(t, s0.IsOwned(&tm), s1.IsOwned(&tm))
+	}
+}
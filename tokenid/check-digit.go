@@ -0,0 +1,143 @@
+package tokenid
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type CheckDigitAlgorithm string
+
+const (
+	CheckDigitNone           CheckDigitAlgorithm = "none"
+	CheckDigitLuhnModN       CheckDigitAlgorithm = "luhn-mod-n"
+	CheckDigitISO7064Mod11_2 CheckDigitAlgorithm = "iso7064-mod-11-2"
+	CheckDigitISO7064Mod37_2 CheckDigitAlgorithm = "iso7064-mod-37-2"
+	CheckDigitISO7064Mod1110 CheckDigitAlgorithm = "iso7064-mod-11-10"
+	CheckDigitISO7064Mod3736 CheckDigitAlgorithm = "iso7064-mod-37-36"
+
+	iso7064Mod11_2CheckChars = AlphabetNumeric + "X"
+	iso7064Mod37_2CheckChars = AlphabetAlphaNumeric + "*"
+)
+
+var ErrCharNotInAlphabet = errors.New("char not in alphabet")
+
+func (a CheckDigitAlgorithm) IsNone() bool {
+	return a == "" || a == CheckDigitNone
+}
+
+// Charset the chars the algorithm is defined on: the ISO 7064 ones have a fixed charset, Luhn mod N works on the alphabet of the format.
+func (a CheckDigitAlgorithm) Charset(alphabet string) string {
+	switch a {
+	case CheckDigitISO7064Mod11_2, CheckDigitISO7064Mod1110:
+		return AlphabetNumeric
+	case CheckDigitISO7064Mod37_2, CheckDigitISO7064Mod3736:
+		return AlphabetAlphaNumeric
+	}
+
+	return alphabet
+}
+
+func (a CheckDigitAlgorithm) Validate() error {
+	switch a {
+	case "", CheckDigitNone, CheckDigitLuhnModN, CheckDigitISO7064Mod11_2, CheckDigitISO7064Mod37_2, CheckDigitISO7064Mod1110, CheckDigitISO7064Mod3736:
+		return nil
+	}
+
+	return fmt.Errorf("unsupported token id check digit algorithm %s", a)
+}
+
+// Compute the check char of s. alphabet is used by Luhn mod N only.
+func (a CheckDigitAlgorithm) Compute(s string, alphabet string) (byte, error) {
+	switch a {
+	case CheckDigitLuhnModN:
+		return LuhnModNCheckDigit(s, alphabet)
+	case CheckDigitISO7064Mod11_2:
+		return ISO7064PureCheckDigit(s, AlphabetNumeric, 11, 2, iso7064Mod11_2CheckChars)
+	case CheckDigitISO7064Mod37_2:
+		return ISO7064PureCheckDigit(s, AlphabetAlphaNumeric, 37, 2, iso7064Mod37_2CheckChars)
+	case CheckDigitISO7064Mod1110:
+		return ISO7064HybridCheckDigit(s, AlphabetNumeric)
+	case CheckDigitISO7064Mod3736:
+		return ISO7064HybridCheckDigit(s, AlphabetAlphaNumeric)
+	}
+
+	return 0, a.Validate()
+}
+
+// Verify checks s is terminated by its check char.
+func (a CheckDigitAlgorithm) Verify(s string, alphabet string) bool {
+	if a.IsNone() {
+		return true
+	}
+
+	if len(s) < 2 {
+		return false
+	}
+
+	c, err := a.Compute(s[:len(s)-1], alphabet)
+	return err == nil && c == s[len(s)-1]
+}
+
+// LuhnModNCheckDigit computes the Luhn mod N check character of s over alphabet (N is the size of the alphabet).
+func LuhnModNCheckDigit(s string, alphabet string) (byte, error) {
+	n := len(alphabet)
+	if n < 2 {
+		return 0, errors.New("alphabet too short")
+	}
+
+	factor := 2
+	sum := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		cp := strings.IndexByte(alphabet, s[i])
+		if cp < 0 {
+			return 0, fmt.Errorf("%w: %q", ErrCharNotInAlphabet, s[i])
+		}
+
+		addend := factor * cp
+		factor = 3 - factor
+		sum += addend/n + addend%n
+	}
+
+	return alphabet[(n-sum%n)%n], nil
+}
+
+// LuhnModNValid checks s is terminated by its Luhn mod N check character.
+func LuhnModNValid(s string, alphabet string) bool {
+	return CheckDigitLuhnModN.Verify(s, alphabet)
+}
+
+// ISO7064PureCheckDigit check char of the ISO 7064 pure systems MOD modulus-radix with a single check char (i.e. MOD 11-2, MOD 37-2).
+// checkChars maps the check values, it may extend the charset (the 'X' of MOD 11-2).
+func ISO7064PureCheckDigit(s string, charset string, modulus int, radix int, checkChars string) (byte, error) {
+	p := 0
+	for i := 0; i < len(s); i++ {
+		v := strings.IndexByte(charset, s[i])
+		if v < 0 {
+			return 0, fmt.Errorf("%w: %q", ErrCharNotInAlphabet, s[i])
+		}
+		p = ((p + v) * radix) % modulus
+	}
+
+	return checkChars[(modulus+1-p)%modulus], nil
+}
+
+// ISO7064HybridCheckDigit check char of the ISO 7064 hybrid systems MOD M+1,M where M is the size of the charset (i.e. MOD 11,10, MOD 37,36).
+func ISO7064HybridCheckDigit(s string, charset string) (byte, error) {
+	m := len(charset)
+	p := m
+	for i := 0; i < len(s); i++ {
+		v := strings.IndexByte(charset, s[i])
+		if v < 0 {
+			return 0, fmt.Errorf("%w: %q", ErrCharNotInAlphabet, s[i])
+		}
+
+		p = (p + v) % m
+		if p == 0 {
+			p = m
+		}
+		p = (p * 2) % (m + 1)
+	}
+
+	return charset[(m+1-p)%m], nil
+}
@@ -0,0 +1,167 @@
+package tokenid
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	ContextIdPlaceHolder = "{context-id}"
+
+	AlphabetNumeric      = "0123456789"
+	AlphabetAlphaNumeric = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+	FormatKeyPrefix     = "prefix"
+	FormatKeyLength     = "length"
+	FormatKeyAlphabet   = "alphabet"
+	FormatKeyCheckDigit = "check"
+)
+
+// Format shape of the ids of a context: prefix (possibly referencing the context id), a body of Length chars of the alphabet
+// and an optional check char computed over prefix and body.
+type Format struct {
+	Prefix     string              `yaml:"prefix,omitempty" mapstructure:"prefix,omitempty" json:"prefix,omitempty"`
+	Length     int                 `yaml:"length,omitempty" mapstructure:"length,omitempty" json:"length,omitempty"`
+	Alphabet   string              `yaml:"alphabet,omitempty" mapstructure:"alphabet,omitempty" json:"alphabet,omitempty"`
+	CheckDigit CheckDigitAlgorithm `yaml:"check,omitempty" mapstructure:"check,omitempty" json:"check,omitempty"`
+}
+
+// DefaultFormat the context id followed by 16 alphanumeric chars and a check char.
+var DefaultFormat = Format{
+	Prefix:     ContextIdPlaceHolder,
+	Length:     16,
+	Alphabet:   AlphabetAlphaNumeric,
+	CheckDigit: CheckDigitLuhnModN,
+}
+
+// ParseFormat parses the Format of a TokenIdProviderType, a ';' separated list of key=value (i.e. prefix={context-id};length=16;alphabet=0123456789;check=luhn-mod-n).
+// Keys not specified take the value of the DefaultFormat; the empty string is the DefaultFormat.
+func ParseFormat(s string) (Format, error) {
+	f := DefaultFormat
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return f, nil
+	}
+
+	for _, kv := range strings.Split(s, ";") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return f, fmt.Errorf("invalid token id format component %q", kv)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(k)) {
+		case FormatKeyPrefix:
+			f.Prefix = strings.TrimSpace(v)
+		case FormatKeyLength:
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return f, fmt.Errorf("invalid token id format length %q", v)
+			}
+			f.Length = n
+		case FormatKeyAlphabet:
+			f.Alphabet = strings.TrimSpace(v)
+		case FormatKeyCheckDigit:
+			f.CheckDigit = CheckDigitAlgorithm(strings.ToLower(strings.TrimSpace(v)))
+		default:
+			return f, fmt.Errorf("unknown token id format key %q", k)
+		}
+	}
+
+	return f, f.Validate()
+}
+
+func (f *Format) Validate() error {
+	if f.Length <= 0 {
+		return errors.New("token id format length must be positive")
+	}
+
+	if len(f.Alphabet) < 2 {
+		return errors.New("token id format alphabet too short")
+	}
+
+	for i := 0; i < len(f.Alphabet); i++ {
+		if strings.IndexByte(f.Alphabet[i+1:], f.Alphabet[i]) >= 0 {
+			return fmt.Errorf("token id format alphabet has duplicate char %q", f.Alphabet[i])
+		}
+	}
+
+	if err := f.CheckDigit.Validate(); err != nil {
+		return err
+	}
+
+	// The check char covers the prefix too, so its literal chars and the alphabet have to belong to the charset of the algorithm.
+	if !f.CheckDigit.IsNone() {
+		charset := f.CheckDigit.Charset(f.Alphabet)
+		lit := strings.ReplaceAll(f.Prefix, ContextIdPlaceHolder, "") + f.Alphabet
+		for i := 0; i < len(lit); i++ {
+			if strings.IndexByte(charset, lit[i]) < 0 {
+				return fmt.Errorf("token id format char %q not supported by check digit %s", lit[i], f.CheckDigit)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ResolvePrefix the prefix of the ids of the context.
+func (f *Format) ResolvePrefix(ctxId string) string {
+	return strings.ReplaceAll(f.Prefix, ContextIdPlaceHolder, strings.ToUpper(ctxId))
+}
+
+// Compose builds the id of the context with the given body appending the check char if required.
+func (f *Format) Compose(ctxId string, body string) (string, error) {
+	if len(body) != f.Length {
+		return "", fmt.Errorf("token id body %q is not %d chars long", body, f.Length)
+	}
+
+	id := f.ResolvePrefix(ctxId) + body
+	if !f.CheckDigit.IsNone() {
+		c, err := f.CheckDigit.Compute(id, f.Alphabet)
+		if err != nil {
+			return "", err
+		}
+		id += string(c)
+	}
+
+	return id, nil
+}
+
+// Random a new id of the context with a random body.
+func (f *Format) Random(ctxId string) (string, error) {
+	max := big.NewInt(int64(len(f.Alphabet)))
+	body := make([]byte, f.Length)
+	for i := range body {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		body[i] = f.Alphabet[n.Int64()]
+	}
+
+	return f.Compose(ctxId, string(body))
+}
+
+// FromSequence the id of the context whose body is the value of a sequence written in the base of the alphabet and left padded.
+func (f *Format) FromSequence(ctxId string, seq uint64) (string, error) {
+	base := uint64(len(f.Alphabet))
+	body := make([]byte, f.Length)
+	for i := len(body) - 1; i >= 0; i-- {
+		body[i] = f.Alphabet[seq%base]
+		seq /= base
+	}
+
+	if seq != 0 {
+		return "", fmt.Errorf("token id sequence value does not fit in %d chars", f.Length)
+	}
+
+	return f.Compose(ctxId, string(body))
+}
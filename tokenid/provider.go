@@ -0,0 +1,195 @@
+package tokenid
+
+import (
+	"errors"
+	"fmt"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/bridgeclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/rs/zerolog/log"
+	"strings"
+	"sync"
+)
+
+// BridgeProvider delegates the generation of the ids to the legacy system reached through the bridge.
+type BridgeProvider struct {
+	client  *bridgeclient.Client
+	reqOpts []bridgeclient.APIRequestContextOption
+}
+
+// NewBridgeProvider the options are applied to the request context of each call; a request id is generated if none is set.
+func NewBridgeProvider(cli *bridgeclient.Client, reqOpts ...bridgeclient.APIRequestContextOption) *BridgeProvider {
+	return &BridgeProvider{client: cli, reqOpts: reqOpts}
+}
+
+func (p *BridgeProvider) NewId(ctxId string, unique bool, action map[string]interface{}) (string, error) {
+	const semLogContext = "token-id-provider::bridge-new-id"
+
+	reqCtx := bridgeclient.NewApiRequestContext(p.reqOpts...)
+	if reqCtx.RequestId == "" {
+		bridgeclient.ApiRequestWithAutoRequestId()(&reqCtx)
+	}
+
+	resp, err := p.client.NewId(reqCtx, ctxId, unique, action)
+	if err != nil {
+		log.Error().Err(err).Str("ctx-id", ctxId).Msg(semLogContext)
+		return token.NilTokenId, err
+	}
+
+	if resp == nil || resp.Id == "" {
+		err = errors.New("bridge returned an empty token id")
+		log.Error().Err(err).Str("ctx-id", ctxId).Msg(semLogContext)
+		return token.NilTokenId, err
+	}
+
+	return resp.Id, nil
+}
+
+// LocalProvider generates random ids of the given format. Uniqueness is probabilistic: the format should leave enough room
+// (alphabet size to the power of the length) for the number of tokens of the context.
+type LocalProvider struct {
+	format Format
+}
+
+func NewLocalProvider(f Format) (*LocalProvider, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &LocalProvider{format: f}, nil
+}
+
+func (p *LocalProvider) NewId(ctxId string, unique bool, action map[string]interface{}) (string, error) {
+	return p.format.Random(ctxId)
+}
+
+// Sequence source of the values of the sequence provider, one sequence per context.
+type Sequence interface {
+	Next(ctxId string) (uint64, error)
+}
+
+// MemorySequence an in process Sequence: values restart at each run so it fits simulators and tests.
+type MemorySequence struct {
+	mu     sync.Mutex
+	start  uint64
+	values map[string]uint64
+}
+
+func NewMemorySequence(start uint64) *MemorySequence {
+	return &MemorySequence{start: start, values: make(map[string]uint64)}
+}
+
+func (s *MemorySequence) Next(ctxId string) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctxId = strings.ToUpper(ctxId)
+	v, ok := s.values[ctxId]
+	if !ok {
+		v = s.start
+	}
+	s.values[ctxId] = v + 1
+	return v, nil
+}
+
+// SequenceProvider ids of the given format whose body encodes the next value of the sequence of the context.
+type SequenceProvider struct {
+	format   Format
+	sequence Sequence
+}
+
+func NewSequenceProvider(f Format, seq Sequence) (*SequenceProvider, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	if seq == nil {
+		return nil, errors.New("token id sequence provider requires a sequence")
+	}
+
+	return &SequenceProvider{format: f, sequence: seq}, nil
+}
+
+func (p *SequenceProvider) NewId(ctxId string, unique bool, action map[string]interface{}) (string, error) {
+	v, err := p.sequence.Next(ctxId)
+	if err != nil {
+		return token.NilTokenId, err
+	}
+
+	return p.format.FromSequence(ctxId, v)
+}
+
+type ProviderOptions struct {
+	BridgeClient      *bridgeclient.Client
+	BridgeRequestOpts []bridgeclient.APIRequestContextOption
+	Sequence          Sequence
+}
+
+type ProviderOption func(opts *ProviderOptions)
+
+func ProviderWithBridgeClient(cli *bridgeclient.Client, reqOpts ...bridgeclient.APIRequestContextOption) ProviderOption {
+	return func(opts *ProviderOptions) {
+		opts.BridgeClient = cli
+		opts.BridgeRequestOpts = reqOpts
+	}
+}
+
+func ProviderWithSequence(seq Sequence) ProviderOption {
+	return func(opts *ProviderOptions) {
+		opts.Sequence = seq
+	}
+}
+
+// NewProvider the provider of the type configured in the context: external requires a bridge client, cos-seq requires the sequence
+// of the options (i.e. ProviderWithSequence(NewMemorySequence(1)) in tests), default (or a nil type) generates random ids. The Format of the type applies to default and cos-seq.
+func NewProvider(pt *token.TokenIdProviderType, opts ...ProviderOption) (token.TokenIdProvider, error) {
+	const semLogContext = "token-id-provider::new"
+
+	pOpts := ProviderOptions{}
+	for _, o := range opts {
+		o(&pOpts)
+	}
+
+	providerType := token.TokenIdProviderTypeDefault
+	format := ""
+	if pt != nil {
+		if pt.ProviderType != "" {
+			providerType = pt.ProviderType
+		}
+		format = pt.Format
+	}
+
+	switch providerType {
+	case token.TokenIdProviderTypeExternal:
+		if pOpts.BridgeClient == nil {
+			return nil, errors.New("external token id provider requires a bridge client")
+		}
+		return NewBridgeProvider(pOpts.BridgeClient, pOpts.BridgeRequestOpts...), nil
+
+	case token.TokenIdProviderTypeDefault:
+		f, err := ParseFormat(format)
+		if err != nil {
+			return nil, err
+		}
+		return NewLocalProvider(f)
+
+	case token.TokenIdProviderTypeCosSequence:
+		f, err := ParseFormat(format)
+		if err != nil {
+			return nil, err
+		}
+
+		if pOpts.Sequence == nil {
+			err = errors.New("cos-seq token id provider requires a sequence")
+			log.Error().Err(err).Msg(semLogContext)
+			return nil, err
+		}
+		return NewSequenceProvider(f, pOpts.Sequence)
+	}
+
+	return nil, fmt.Errorf("unsupported token id provider type %s", providerType)
+}
+
+// NewContextProvider the provider configured in the token context.
+func NewContextProvider(ctx *token.TokenContext, opts ...ProviderOption) (token.TokenIdProvider, error) {
+	return NewProvider(ctx.TokenIdProviderType, opts...)
+}
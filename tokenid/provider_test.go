@@ -0,0 +1,50 @@
+package tokenid_test
+
+import (
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokenid"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestProviders(t *testing.T) {
+
+	p, err := tokenid.NewProvider(nil)
+	require.NoError(t, err)
+
+	id, err := p.NewId("bpmgm1", true, nil)
+	require.NoError(t, err)
+	require.Len(t, id, 6+16+1)
+	require.True(t, strings.HasPrefix(id, "BPMGM1"))
+	require.True(t, tokenid.LuhnModNValid(id, tokenid.AlphabetAlphaNumeric))
+
+	cosSeq := &token.TokenIdProviderType{ProviderType: token.TokenIdProviderTypeCosSequence, Format: "prefix=T-;length=4;alphabet=0123456789;check=none"}
+	_, err = tokenid.NewProvider(cosSeq)
+	require.Error(t, err)
+
+	p, err = tokenid.NewProvider(cosSeq, tokenid.ProviderWithSequence(tokenid.NewMemorySequence(1)))
+	require.NoError(t, err)
+
+	id, err = p.NewId("BPMGM1", true, nil)
+	require.NoError(t, err)
+	require.Equal(t, "T-0001", id)
+	id, err = p.NewId("BPMGM1", true, nil)
+	require.NoError(t, err)
+	require.Equal(t, "T-0002", id)
+
+	_, err = tokenid.NewProvider(&token.TokenIdProviderType{ProviderType: token.TokenIdProviderTypeExternal})
+	require.Error(t, err)
+
+	_, err = tokenid.ParseFormat("length=0")
+	require.Error(t, err)
+}
+
+func TestLuhnModN(t *testing.T) {
+
+	c, err := tokenid.LuhnModNCheckDigit("7992739871", tokenid.AlphabetNumeric)
+	require.NoError(t, err)
+	require.Equal(t, byte('3'), c)
+	require.True(t, tokenid.LuhnModNValid("79927398713", tokenid.AlphabetNumeric))
+	require.False(t, tokenid.LuhnModNValid("79927398731", tokenid.AlphabetNumeric))
+}
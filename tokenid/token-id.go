@@ -0,0 +1,163 @@
+package tokenid
+
+import (
+	"errors"
+	"fmt"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+var (
+	ErrInvalidTokenIdLength     = errors.New("invalid token id length")
+	ErrInvalidTokenIdPrefix     = errors.New("invalid token id prefix")
+	ErrInvalidTokenIdChar       = errors.New("invalid token id char")
+	ErrInvalidTokenIdCheckDigit = errors.New("invalid token id check digit")
+)
+
+// Id the components of a token id.
+type Id struct {
+	ContextId  string `yaml:"context-id,omitempty" mapstructure:"context-id,omitempty" json:"context-id,omitempty"`
+	Prefix     string `yaml:"prefix,omitempty" mapstructure:"prefix,omitempty" json:"prefix,omitempty"`
+	Body       string `yaml:"body,omitempty" mapstructure:"body,omitempty" json:"body,omitempty"`
+	CheckDigit string `yaml:"check-digit,omitempty" mapstructure:"check-digit,omitempty" json:"check-digit,omitempty"`
+}
+
+func (id Id) String() string {
+	return id.Prefix + id.Body + id.CheckDigit
+}
+
+// Normalize removes the blanks and, if the alphabet has no lower case letters, upper cases the id.
+func (f *Format) Normalize(id string) string {
+	id = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, id)
+
+	if f.Alphabet == strings.ToUpper(f.Alphabet) {
+		id = strings.ToUpper(id)
+	}
+
+	return id
+}
+
+// Generate a new random id of the context.
+func (f *Format) Generate(ctxId string) (Id, error) {
+	s, err := f.Random(ctxId)
+	if err != nil {
+		return Id{}, err
+	}
+
+	return f.Parse(ctxId, s)
+}
+
+// Parse splits the normalized id in its components verifying length, prefix, alphabet and check char. If ctxId is empty and the prefix
+// references the context id, the context id is taken from the id itself.
+func (f *Format) Parse(ctxId string, s string) (Id, error) {
+	s = f.Normalize(s)
+
+	checkLen := 0
+	if !f.CheckDigit.IsNone() {
+		checkLen = 1
+	}
+
+	var id Id
+	if ctxId != "" || !strings.Contains(f.Prefix, ContextIdPlaceHolder) {
+		id.ContextId = strings.ToUpper(ctxId)
+		id.Prefix = f.ResolvePrefix(ctxId)
+		if len(s) != len(id.Prefix)+f.Length+checkLen {
+			return Id{}, fmt.Errorf("%w: %q", ErrInvalidTokenIdLength, s)
+		}
+
+		if !strings.HasPrefix(s, id.Prefix) {
+			return Id{}, fmt.Errorf("%w: %q", ErrInvalidTokenIdPrefix, s)
+		}
+	} else {
+		pre, post, _ := strings.Cut(f.Prefix, ContextIdPlaceHolder)
+		prefixLen := len(s) - f.Length - checkLen
+		if prefixLen <= len(pre)+len(post) {
+			return Id{}, fmt.Errorf("%w: %q", ErrInvalidTokenIdLength, s)
+		}
+
+		id.Prefix = s[:prefixLen]
+		if !strings.HasPrefix(id.Prefix, pre) || !strings.HasSuffix(id.Prefix, post) {
+			return Id{}, fmt.Errorf("%w: %q", ErrInvalidTokenIdPrefix, s)
+		}
+		id.ContextId = id.Prefix[len(pre) : prefixLen-len(post)]
+	}
+
+	id.Body = s[len(id.Prefix) : len(id.Prefix)+f.Length]
+	for i := 0; i < len(id.Body); i++ {
+		if strings.IndexByte(f.Alphabet, id.Body[i]) < 0 {
+			return Id{}, fmt.Errorf("%w: %q in %q", ErrInvalidTokenIdChar, id.Body[i], s)
+		}
+	}
+
+	if checkLen > 0 {
+		id.CheckDigit = s[len(s)-1:]
+		if !f.CheckDigit.Verify(s, f.Alphabet) {
+			return Id{}, fmt.Errorf("%w: %q", ErrInvalidTokenIdCheckDigit, s)
+		}
+	}
+
+	return id, nil
+}
+
+// ValidateId checks the id belongs to the context and has not been mistyped.
+func (f *Format) ValidateId(ctxId string, s string) error {
+	_, err := f.Parse(ctxId, s)
+	return err
+}
+
+// Validator validates the ids of the contexts it knows the format of. Ids of other contexts are accepted and left to the server.
+type Validator struct {
+	mu      sync.RWMutex
+	formats map[string]Format
+}
+
+func NewValidator() *Validator {
+	return &Validator{formats: make(map[string]Format)}
+}
+
+func (v *Validator) Register(ctxId string, f Format) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.formats[token.WellFormTokenContextId(ctxId)] = f
+	return nil
+}
+
+// RegisterContext registers the format of the context. Only contexts declaring an explicit Format are registered: the ids of the others,
+// and of contexts with an external provider since the legacy system owns the format, are left to the server.
+func (v *Validator) RegisterContext(ctx *token.TokenContext) error {
+	pt := ctx.TokenIdProviderType
+	if pt == nil || pt.ProviderType == token.TokenIdProviderTypeExternal || strings.TrimSpace(pt.Format) == "" {
+		return nil
+	}
+
+	f, err := ParseFormat(pt.Format)
+	if err != nil {
+		return err
+	}
+
+	return v.Register(ctx.Id, f)
+}
+
+// ValidateTokenId validates the id and returns it normalized (see Format.Normalize). Ids of unknown contexts are returned as they are.
+func (v *Validator) ValidateTokenId(ctxId string, tokenId string) (string, error) {
+	v.mu.RLock()
+	f, ok := v.formats[token.WellFormTokenContextId(ctxId)]
+	v.mu.RUnlock()
+	if !ok {
+		return tokenId, nil
+	}
+
+	id := f.Normalize(tokenId)
+	return id, f.ValidateId(ctxId, id)
+}
@@ -0,0 +1,115 @@
+package tokenid_test
+
+import (
+	"errors"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokenid"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestISO7064(t *testing.T) {
+
+	// ORCID uses MOD 11-2.
+	c, err := tokenid.CheckDigitISO7064Mod11_2.Compute("000000021694233", "")
+	require.NoError(t, err)
+	require.Equal(t, byte('X'), c)
+
+	c, err = tokenid.CheckDigitISO7064Mod3736.Compute("A12425GABC1234002", "")
+	require.NoError(t, err)
+	require.Equal(t, byte('M'), c)
+
+	for _, a := range []tokenid.CheckDigitAlgorithm{tokenid.CheckDigitISO7064Mod37_2, tokenid.CheckDigitISO7064Mod1110} {
+		c, err = a.Compute("0794", "")
+		require.NoError(t, err)
+		require.True(t, a.Verify("0794"+string(c), ""))
+		require.False(t, a.Verify("0749"+string(c), ""), a)
+	}
+}
+
+func TestFormatParse(t *testing.T) {
+
+	f, err := tokenid.ParseFormat("check=iso7064-mod-37-36")
+	require.NoError(t, err)
+
+	id, err := f.Generate("bpmgm1")
+	require.NoError(t, err)
+	require.Equal(t, "BPMGM1", id.ContextId)
+
+	p, err := f.Parse("", " "+id.String()[:10]+" "+id.String()[10:])
+	require.NoError(t, err)
+	require.Equal(t, id, p)
+
+	// adjacent transposition of a fixed id.
+	require.NoError(t, f.ValidateId("BPMGM1", "BPMGM10123456789ABCDEFC"))
+	require.ErrorIs(t, f.ValidateId("BPMGM1", "BPMGM10132456789ABCDEFC"), tokenid.ErrInvalidTokenIdCheckDigit)
+
+	require.ErrorIs(t, f.ValidateId("BPMGM2", id.String()), tokenid.ErrInvalidTokenIdPrefix)
+	require.ErrorIs(t, f.ValidateId("BPMGM1", id.String()[1:]), tokenid.ErrInvalidTokenIdLength)
+
+	_, err = tokenid.ParseFormat("alphabet=abc;check=iso7064-mod-11-2")
+	require.Error(t, err)
+}
+
+func TestTokenCheckRejection(t *testing.T) {
+
+	v := tokenid.NewValidator()
+	require.NoError(t, v.RegisterContext(&token.TokenContext{Id: "BPMGM1", TokenIdProviderType: &token.TokenIdProviderType{Format: "prefix={context-id};length=16;check=iso7064-mod-37-36"}}))
+
+	// contexts without an explicit format are left to the server.
+	require.NoError(t, v.RegisterContext(&token.TokenContext{Id: "BPMGM2"}))
+	id, err := v.ValidateTokenId("BPMGM2", "BPMGM2 ABC")
+	require.NoError(t, err)
+	require.Equal(t, "BPMGM2 ABC", id)
+
+	id, err = v.ValidateTokenId("BPMGM1", "bpmgm1 0123456789 abcdefc")
+	require.NoError(t, err)
+	require.Equal(t, "BPMGM10123456789ABCDEFC", id)
+
+	var mu sync.Mutex
+	var paths, bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		paths, bodies = append(paths, r.URL.EscapedPath()), append(bodies, string(b))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": "BPMGM10123456789ABCDEFC"}`))
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+
+	cli, err := tokensclient.NewTokensApiClient(&tokensclient.Config{Host: tokensclient.HostInfo{Scheme: "http", HostName: u.Hostname(), Port: port}})
+	require.NoError(t, err)
+	defer cli.Close()
+	cli.SetTokenIdValidator(v)
+
+	_, err = cli.TokenCheck(tokensclient.NewApiRequestContext(), "BPMGM1", "BPMGM1ABC", &tokensclient.TokenApiRequest{}, "")
+	var apiErr *tokensclient.ApiResponse
+	require.True(t, errors.As(err, &apiErr))
+	require.Equal(t, tokensclient.InvalidTokenIdErrorCode, apiErr.ErrCode)
+
+	mu.Lock()
+	require.Empty(t, paths)
+	mu.Unlock()
+
+	// the id is sent normalized, as checked.
+	_, err = cli.TokenCheck(tokensclient.NewApiRequestContext(), "BPMGM1", "BPMGM1 0123456789 ABCDEFC", &tokensclient.TokenApiRequest{TokenId: "BPMGM1 0123456789 ABCDEFC"}, "")
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []string{"/api/v1/token-contexts/BPMGM1/tokens/BPMGM10123456789ABCDEFC/check"}, paths)
+	require.Contains(t, bodies[0], `"token-id":"BPMGM10123456789ABCDEFC"`)
+}
@@ -0,0 +1,161 @@
+package tokensclient
+
+import (
+	"errors"
+	"fmt"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/bearer"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/rs/zerolog/log"
+	"sort"
+	"strings"
+)
+
+type TokenTransferRequest struct {
+	CtxId       string `yaml:"ctx-id,omitempty" mapstructure:"ctx-id,omitempty" json:"ctx-id,omitempty"`
+	TokenId     string `yaml:"token-id,omitempty" mapstructure:"token-id,omitempty" json:"token-id,omitempty"`
+	FromActorId string `yaml:"from-actor-id,omitempty" mapstructure:"from-actor-id,omitempty" json:"from-actor-id,omitempty"`
+	// FromRole the role of the token in the source bearer. If empty it is looked up from the bearer.
+	FromRole  string `yaml:"from-role,omitempty" mapstructure:"from-role,omitempty" json:"from-role,omitempty"`
+	ToActorId string `yaml:"to-actor-id,omitempty" mapstructure:"to-actor-id,omitempty" json:"to-actor-id,omitempty"`
+	// ToRole the role of the token in the target bearer, defaults to bearer.RolePrimary.
+	ToRole string `yaml:"to-role,omitempty" mapstructure:"to-role,omitempty" json:"to-role,omitempty"`
+}
+
+type TokenTransferResult struct {
+	From        *bearer.Bearer `yaml:"from,omitempty" mapstructure:"from,omitempty" json:"from,omitempty"`
+	To          *bearer.Bearer `yaml:"to,omitempty" mapstructure:"to,omitempty" json:"to,omitempty"`
+	Compensated bool           `yaml:"compensated,omitempty" mapstructure:"compensated,omitempty" json:"compensated,omitempty"`
+}
+
+// TransferToken moves a token from the bearer of an actor to the bearer of another one (or changes its role if the actor is the same).
+// The token is removed from the source and then added to the target: if the add fails the token is given back to the source with its
+// original role. If the compensation fails too the returned error reports both failures and the token is left without the source bearer.
+func (c *Client) TransferToken(reqCtx ApiRequestContext, transfer TokenTransferRequest) (*TokenTransferResult, error) {
+	const semLogContext = "tpm-tokens-client::transfer-token"
+
+	if transfer.ToRole == "" {
+		transfer.ToRole = bearer.RolePrimary
+	}
+
+	if !bearer.RoleIsValid(transfer.ToRole) {
+		return nil, NewBadRequestError(WithErrorMessage(fmt.Sprintf("invalid role %s", transfer.ToRole)))
+	}
+
+	if transfer.FromRole == "" {
+		src, err := c.GetBearerInContext(reqCtx, transfer.FromActorId, transfer.CtxId)
+		if err != nil {
+			return nil, err
+		}
+
+		transfer.FromRole = findTokenRole(src, transfer.TokenId)
+		if transfer.FromRole == "" {
+			return nil, NewBadRequestError(WithErrorMessage(fmt.Sprintf("token %s not enlisted in bearer of %s", transfer.TokenId, transfer.FromActorId)))
+		}
+	}
+
+	res := TokenTransferResult{}
+	var err error
+	res.From, err = c.RemoveTokenFromBearerInContext(reqCtx, transfer.FromActorId, transfer.CtxId, transfer.TokenId, transfer.FromRole)
+	if err != nil {
+		log.Error().Err(err).Str("token-id", transfer.TokenId).Str("actor-id", transfer.FromActorId).Msg(semLogContext + " remove from source failed")
+		return nil, err
+	}
+
+	res.To, err = c.AddToken2BearerInContext(reqCtx, transfer.ToActorId, transfer.CtxId, transfer.TokenId, transfer.ToRole)
+	if err == nil {
+		return &res, nil
+	}
+
+	log.Error().Err(err).Str("token-id", transfer.TokenId).Str("actor-id", transfer.ToActorId).Msg(semLogContext + " add to target failed... compensating")
+	from, cerr := c.AddToken2BearerInContext(reqCtx, transfer.FromActorId, transfer.CtxId, transfer.TokenId, transfer.FromRole)
+	if cerr != nil {
+		log.Error().Err(cerr).Str("token-id", transfer.TokenId).Str("actor-id", transfer.FromActorId).Msg(semLogContext + " compensation failed")
+		return &res, NewExecutableServerError(
+			WithErrorMessage(fmt.Sprintf("transfer of token %s failed and token could not be given back to %s", transfer.TokenId, transfer.FromActorId)),
+			WithDescription(errors.Join(err, cerr).Error()))
+	}
+
+	res.From = from
+	res.To = nil
+	res.Compensated = true
+	return &res, err
+}
+
+func findTokenRole(ber *bearer.Bearer, tokId string) string {
+	if ber == nil {
+		return ""
+	}
+
+	tokId = token.WellFormTokenId(tokId)
+	for _, tr := range ber.TokenRefs {
+		if token.WellFormTokenId(tr.Id) == tokId {
+			return tr.Role
+		}
+	}
+
+	return ""
+}
+
+// BearerBulkResult outcome of a bulk operation: the bearer as of the last successful call and the outcome of each token.
+type BearerBulkResult struct {
+	Bearer *bearer.Bearer
+	Done   []string
+	Failed map[string]error
+}
+
+func (r *BearerBulkResult) Err() error {
+	if len(r.Failed) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(r.Failed))
+	for id := range r.Failed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var sb strings.Builder
+	for i, id := range ids {
+		if i > 0 {
+			sb.WriteString("; ")
+		}
+		sb.WriteString(id)
+		sb.WriteString(": ")
+		sb.WriteString(r.Failed[id].Error())
+	}
+
+	return NewExecutableServerError(WithErrorMessage(fmt.Sprintf("%d of %d tokens failed", len(r.Failed), len(r.Failed)+len(r.Done))), WithDescription(sb.String()))
+}
+
+// EnlistTokens adds the tokens to the bearer of the actor with the same role. Failures do not stop the operation.
+func (c *Client) EnlistTokens(reqCtx ApiRequestContext, actorId, ctxId string, tokIds []string, role string) (*BearerBulkResult, error) {
+	return c.bulkBearerOp(tokIds, func(tokId string) (*bearer.Bearer, error) {
+		return c.AddToken2BearerInContext(reqCtx, actorId, ctxId, tokId, role)
+	})
+}
+
+// UnenlistTokens removes the tokens from the bearer of the actor. Failures do not stop the operation.
+func (c *Client) UnenlistTokens(reqCtx ApiRequestContext, actorId, ctxId string, tokIds []string, role string) (*BearerBulkResult, error) {
+	return c.bulkBearerOp(tokIds, func(tokId string) (*bearer.Bearer, error) {
+		return c.RemoveTokenFromBearerInContext(reqCtx, actorId, ctxId, tokId, role)
+	})
+}
+
+func (c *Client) bulkBearerOp(tokIds []string, op func(tokId string) (*bearer.Bearer, error)) (*BearerBulkResult, error) {
+	const semLogContext = "tpm-tokens-client::bulk-bearer-op"
+
+	res := BearerBulkResult{Failed: make(map[string]error)}
+	for _, tokId := range tokIds {
+		ber, err := op(tokId)
+		if err != nil {
+			log.Error().Err(err).Str("token-id", tokId).Msg(semLogContext)
+			res.Failed[tokId] = err
+			continue
+		}
+
+		res.Bearer = ber
+		res.Done = append(res.Done, tokId)
+	}
+
+	return &res, res.Err()
+}
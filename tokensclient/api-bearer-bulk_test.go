@@ -0,0 +1,99 @@
+package tokensclient_test
+
+import (
+	"encoding/json"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/bearer"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// bearerCalls the calls received by the test server: the handler only records them, the test asserts on them.
+type bearerCalls struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (c *bearerCalls) add(s string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, s)
+}
+
+// take returns the calls recorded so far and resets them.
+func (c *bearerCalls) take() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	calls := c.calls
+	c.calls = nil
+	return calls
+}
+
+// newBearerTestServer serves the add/remove token endpoints. Adds to the bearers of failingActor fail.
+func newBearerTestServer(t *testing.T, failingActor string, calls *bearerCalls) (*tokensclient.Client, func()) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// /api/v1/bearers/{actor-id}/{context-id}/{token-id}
+		comps := strings.Split(strings.TrimPrefix(r.URL.Path, tokensclient.BearerBasePath+"/"), "/")
+		if len(comps) != 3 {
+			calls.add(r.Method + " unexpected path " + r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		calls.add(r.Method + " " + comps[0] + " " + comps[2])
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost && comps[0] == failingActor {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error-code": "bearer-err", "text": "unavailable"}`))
+			return
+		}
+
+		b := bearer.Bearer{ActorId: comps[0], TokenContextId: comps[1]}
+		if r.Method == http.MethodPost {
+			b.TokenRefs = []bearer.TokenRef{{Id: comps[2], Role: r.URL.Query().Get("role")}}
+		}
+		_ = json.NewEncoder(w).Encode(&b)
+	}))
+
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+	cli, err := tokensclient.NewTokensApiClient(&tokensclient.Config{Host: tokensclient.HostInfo{Scheme: "http", HostName: u.Hostname(), Port: port}})
+	require.NoError(t, err)
+	return cli, srv.Close
+}
+
+func TestTransferToken(t *testing.T) {
+
+	calls := &bearerCalls{}
+	cli, closer := newBearerTestServer(t, "B", calls)
+	defer closer()
+
+	reqCtx := tokensclient.NewApiRequestContext()
+	res, err := cli.TransferToken(reqCtx, tokensclient.TokenTransferRequest{CtxId: "CTX", TokenId: "TOK1", FromActorId: "A", FromRole: bearer.RoleSecondary, ToActorId: "C"})
+	require.NoError(t, err)
+	require.False(t, res.Compensated)
+	require.Equal(t, bearer.RolePrimary, res.To.TokenRefs[0].Role)
+	require.Equal(t, []string{"DELETE A TOK1", "POST C TOK1"}, calls.take())
+
+	res, err = cli.TransferToken(reqCtx, tokensclient.TokenTransferRequest{CtxId: "CTX", TokenId: "TOK1", FromActorId: "A", FromRole: bearer.RoleSecondary, ToActorId: "B"})
+	require.Error(t, err)
+	require.True(t, res.Compensated)
+	require.Equal(t, bearer.RoleSecondary, res.From.TokenRefs[0].Role)
+	require.Equal(t, []string{"DELETE A TOK1", "POST B TOK1", "POST A TOK1"}, calls.take())
+
+	bulk, err := cli.EnlistTokens(reqCtx, "B", "CTX", []string{"TOK1", "TOK2"}, bearer.RolePrimary)
+	require.Error(t, err)
+	require.Len(t, bulk.Failed, 2)
+	require.ElementsMatch(t, []string{"POST B TOK1", "POST B TOK2"}, calls.take())
+
+	bulk, err = cli.UnenlistTokens(reqCtx, "B", "CTX", []string{"TOK1", "TOK2"}, bearer.RolePrimary)
+	require.NoError(t, err)
+	require.Equal(t, []string{"TOK1", "TOK2"}, bulk.Done)
+	require.ElementsMatch(t, []string{"DELETE B TOK1", "DELETE B TOK2"}, calls.take())
+}
@@ -21,10 +21,14 @@ type BearerApiRequest struct {
 }
 
 func (c *Client) QueryBearers(reqCtx ApiRequestContext, actorId string) (*bearer.BearersQueryResponse, error) {
+	return c.queryBearers(reqCtx, bearer.WellFormBearerId(actorId))
+}
+
+func (c *Client) queryBearers(reqCtx ApiRequestContext, actorSegment string) (*bearer.BearersQueryResponse, error) {
 	const semLogContext = "tpm-tokens-client::query-bearers"
 	log.Trace().Msg(semLogContext)
 
-	ep := c.bearerApiUrl(BearersByActorId, actorId, "", "", nil)
+	ep := c.bearerApiUrl(BearersByActorId, actorSegment, "", "", nil)
 
 	req, err := c.client.NewRequest(http.MethodGet, ep, nil, reqCtx.getHeaders(""), nil)
 	if err != nil {
@@ -46,10 +50,14 @@ func (c *Client) QueryBearers(reqCtx ApiRequestContext, actorId string) (*bearer
 }
 
 func (c *Client) GetBearerInContext(reqCtx ApiRequestContext, actorId, ctxId string) (*bearer.Bearer, error) {
+	return c.getBearerInContext(reqCtx, bearer.WellFormBearerId(actorId), ctxId)
+}
+
+func (c *Client) getBearerInContext(reqCtx ApiRequestContext, actorSegment, ctxId string) (*bearer.Bearer, error) {
 	const semLogContext = "tpm-tokens-client::get-bearer-in-ctx"
 	log.Trace().Msg(semLogContext)
 
-	ep := c.bearerApiUrl(BearerContextGet, actorId, ctxId, "", nil)
+	ep := c.bearerApiUrl(BearerContextGet, actorSegment, ctxId, "", nil)
 
 	req, err := c.client.NewRequest(http.MethodGet, ep, nil, reqCtx.getHeaders(""), nil)
 	if err != nil {
@@ -70,11 +78,15 @@ func (c *Client) GetBearerInContext(reqCtx ApiRequestContext, actorId, ctxId str
 	return resp, err
 }
 
-func (c *Client) AddBearer2Context(reqCtx ApiRequestContext, actorId, ctxId string, bearer *BearerApiRequest, ct string) (*bearer.Bearer, error) {
+func (c *Client) AddBearer2Context(reqCtx ApiRequestContext, actorId, ctxId string, bearerReq *BearerApiRequest, ct string) (*bearer.Bearer, error) {
+	return c.addBearer2Context(reqCtx, bearer.WellFormBearerId(actorId), ctxId, bearerReq, ct)
+}
+
+func (c *Client) addBearer2Context(reqCtx ApiRequestContext, actorSegment, ctxId string, bearer *BearerApiRequest, ct string) (*bearer.Bearer, error) {
 	const semLogContext = "tpm-tokens-client::add-bearer-2-ctx"
 	log.Trace().Msg(semLogContext)
 
-	ep := c.bearerApiUrl(BearerContextPost, actorId, ctxId, "", nil)
+	ep := c.bearerApiUrl(BearerContextPost, actorSegment, ctxId, "", nil)
 
 	if ct == "" {
 		ct = ContentTypeApplicationJson
@@ -111,11 +123,15 @@ func (c *Client) AddBearer2Context(reqCtx ApiRequestContext, actorId, ctxId stri
 	return resp, err
 }
 
-func (c *Client) UpdateBearerInContext(reqCtx ApiRequestContext, actorId, ctxId string, bearer *BearerApiRequest, ct string) (*bearer.Bearer, error) {
+func (c *Client) UpdateBearerInContext(reqCtx ApiRequestContext, actorId, ctxId string, bearerReq *BearerApiRequest, ct string) (*bearer.Bearer, error) {
+	return c.updateBearerInContext(reqCtx, bearer.WellFormBearerId(actorId), ctxId, bearerReq, ct)
+}
+
+func (c *Client) updateBearerInContext(reqCtx ApiRequestContext, actorSegment, ctxId string, bearer *BearerApiRequest, ct string) (*bearer.Bearer, error) {
 	const semLogContext = "tpm-tokens-client::update-bearer-in-ctx"
 	log.Trace().Msg(semLogContext)
 
-	ep := c.bearerApiUrl(BearerContextPut, actorId, ctxId, "", nil)
+	ep := c.bearerApiUrl(BearerContextPut, actorSegment, ctxId, "", nil)
 
 	if ct == "" {
 		ct = ContentTypeApplicationJson
@@ -153,10 +169,14 @@ func (c *Client) UpdateBearerInContext(reqCtx ApiRequestContext, actorId, ctxId
 }
 
 func (c *Client) RemoveBearerFromContext(reqCtx ApiRequestContext, actorId, ctxId string) (*bearer.Bearer, error) {
+	return c.removeBearerFromContext(reqCtx, bearer.WellFormBearerId(actorId), ctxId)
+}
+
+func (c *Client) removeBearerFromContext(reqCtx ApiRequestContext, actorSegment, ctxId string) (*bearer.Bearer, error) {
 	const semLogContext = "tpm-tokens-client::remove-bearer-from-ctx"
 	log.Trace().Msg(semLogContext)
 
-	ep := c.bearerApiUrl(BearerContextDelete, actorId, ctxId, "", nil)
+	ep := c.bearerApiUrl(BearerContextDelete, actorSegment, ctxId, "", nil)
 
 	req, err := c.client.NewRequest(http.MethodDelete, ep, nil, reqCtx.getHeaders(""), nil)
 	if err != nil {
@@ -178,10 +198,14 @@ func (c *Client) RemoveBearerFromContext(reqCtx ApiRequestContext, actorId, ctxI
 }
 
 func (c *Client) AddToken2BearerInContext(reqCtx ApiRequestContext, actorId, ctxId, tokId string, role string) (*bearer.Bearer, error) {
+	return c.addToken2BearerInContext(reqCtx, bearer.WellFormBearerId(actorId), ctxId, tokId, role)
+}
+
+func (c *Client) addToken2BearerInContext(reqCtx ApiRequestContext, actorSegment, ctxId, tokId string, role string) (*bearer.Bearer, error) {
 	const semLogContext = "tpm-tokens-client::add-token-2-bearer-in-ctx"
 	log.Trace().Msg(semLogContext)
 
-	ep := c.bearerApiUrl(AddToken2BearerInContextPost, actorId, ctxId, tokId, []har.NameValuePair{{Name: "role", Value: role}})
+	ep := c.bearerApiUrl(AddToken2BearerInContextPost, actorSegment, ctxId, tokId, []har.NameValuePair{{Name: "role", Value: role}})
 
 	req, err := c.client.NewRequest(http.MethodPost, ep, nil, reqCtx.getHeaders(""), nil)
 	if err != nil {
@@ -203,10 +227,14 @@ func (c *Client) AddToken2BearerInContext(reqCtx ApiRequestContext, actorId, ctx
 }
 
 func (c *Client) RemoveTokenFromBearerInContext(reqCtx ApiRequestContext, actorId, ctxId, tokId string, role string) (*bearer.Bearer, error) {
+	return c.removeTokenFromBearerInContext(reqCtx, bearer.WellFormBearerId(actorId), ctxId, tokId, role)
+}
+
+func (c *Client) removeTokenFromBearerInContext(reqCtx ApiRequestContext, actorSegment, ctxId, tokId string, role string) (*bearer.Bearer, error) {
 	const semLogContext = "tpm-tokens-client::remove-token-from-bearer-in-ctx"
 	log.Trace().Msg(semLogContext)
 
-	ep := c.bearerApiUrl(RemoveTokenFromBearerInContextDelete, actorId, ctxId, tokId, []har.NameValuePair{{Name: "role", Value: role}})
+	ep := c.bearerApiUrl(RemoveTokenFromBearerInContextDelete, actorSegment, ctxId, tokId, []har.NameValuePair{{Name: "role", Value: role}})
 
 	req, err := c.client.NewRequest(http.MethodDelete, ep, nil, reqCtx.getHeaders(""), nil)
 	if err != nil {
@@ -227,7 +255,72 @@ func (c *Client) RemoveTokenFromBearerInContext(reqCtx ApiRequestContext, actorI
 	return resp, err
 }
 
-func (c *Client) bearerApiUrl(apiPath string, actorId, ctxId, tokId string, qParams []har.NameValuePair) string {
+// QueryActorBearers typed version of QueryBearers.
+func (c *Client) QueryActorBearers(reqCtx ApiRequestContext, actor bearer.ActorId) (*bearer.BearersQueryResponse, error) {
+	if actor.IsZero() {
+		return nil, NewBadRequestError(WithErrorMessage("actor id missing"))
+	}
+
+	return c.queryBearers(reqCtx, actor.PathSegment())
+}
+
+// GetBearer typed version of GetBearerInContext.
+func (c *Client) GetBearer(reqCtx ApiRequestContext, bearerId bearer.BearerId) (*bearer.Bearer, error) {
+	if bearerId.IsZero() {
+		return nil, NewBadRequestError(WithErrorMessage("bearer id missing"))
+	}
+
+	return c.getBearerInContext(reqCtx, bearerId.Actor().PathSegment(), bearerId.ContextId())
+}
+
+// AddBearer typed version of AddBearer2Context.
+func (c *Client) AddBearer(reqCtx ApiRequestContext, bearerId bearer.BearerId, bearerReq *BearerApiRequest, ct string) (*bearer.Bearer, error) {
+	if bearerId.IsZero() {
+		return nil, NewBadRequestError(WithErrorMessage("bearer id missing"))
+	}
+
+	return c.addBearer2Context(reqCtx, bearerId.Actor().PathSegment(), bearerId.ContextId(), bearerReq, ct)
+}
+
+// UpdateBearer typed version of UpdateBearerInContext.
+func (c *Client) UpdateBearer(reqCtx ApiRequestContext, bearerId bearer.BearerId, bearerReq *BearerApiRequest, ct string) (*bearer.Bearer, error) {
+	if bearerId.IsZero() {
+		return nil, NewBadRequestError(WithErrorMessage("bearer id missing"))
+	}
+
+	return c.updateBearerInContext(reqCtx, bearerId.Actor().PathSegment(), bearerId.ContextId(), bearerReq, ct)
+}
+
+// RemoveBearer typed version of RemoveBearerFromContext.
+func (c *Client) RemoveBearer(reqCtx ApiRequestContext, bearerId bearer.BearerId) (*bearer.Bearer, error) {
+	if bearerId.IsZero() {
+		return nil, NewBadRequestError(WithErrorMessage("bearer id missing"))
+	}
+
+	return c.removeBearerFromContext(reqCtx, bearerId.Actor().PathSegment(), bearerId.ContextId())
+}
+
+// AddToken2Bearer typed version of AddToken2BearerInContext.
+func (c *Client) AddToken2Bearer(reqCtx ApiRequestContext, bearerId bearer.BearerId, tokId string, role string) (*bearer.Bearer, error) {
+	if bearerId.IsZero() {
+		return nil, NewBadRequestError(WithErrorMessage("bearer id missing"))
+	}
+
+	return c.addToken2BearerInContext(reqCtx, bearerId.Actor().PathSegment(), bearerId.ContextId(), tokId, role)
+}
+
+// RemoveTokenFromBearer typed version of RemoveTokenFromBearerInContext.
+func (c *Client) RemoveTokenFromBearer(reqCtx ApiRequestContext, bearerId bearer.BearerId, tokId string, role string) (*bearer.Bearer, error) {
+	if bearerId.IsZero() {
+		return nil, NewBadRequestError(WithErrorMessage("bearer id missing"))
+	}
+
+	return c.removeTokenFromBearerInContext(reqCtx, bearerId.Actor().PathSegment(), bearerId.ContextId(), tokId, role)
+}
+
+// bearerApiUrl the actor segment is put in the path as is: the string apis well form the actor id, the typed ones use its canonical
+// escaped form (bearer.ActorId.PathSegment).
+func (c *Client) bearerApiUrl(apiPath string, actorSegment, ctxId, tokId string, qParams []har.NameValuePair) string {
 	var sb = strings.Builder{}
 	sb.WriteString(c.host.Scheme)
 	sb.WriteString("://")
@@ -236,7 +329,7 @@ func (c *Client) bearerApiUrl(apiPath string, actorId, ctxId, tokId string, qPar
 	sb.WriteString(fmt.Sprint(c.host.Port))
 
 	apiPath = strings.Replace(apiPath, TokenContextIdPathPlaceHolder, token.WellFormTokenContextId(ctxId), 1)
-	apiPath = strings.Replace(apiPath, ActorIdPathPlaceHolder, bearer.WellFormBearerId(actorId), 1)
+	apiPath = strings.Replace(apiPath, ActorIdPathPlaceHolder, actorSegment, 1)
 	apiPath = strings.Replace(apiPath, TokenIdPathPlaceHolder, token.WellFormTokenId(tokId), 1)
 	sb.WriteString(apiPath)
 
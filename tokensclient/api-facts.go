@@ -2,6 +2,7 @@ package tokensclient
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-archive/har"
 	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-client/restclient"
@@ -10,7 +11,9 @@ import (
 	"github.com/rs/zerolog/log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type FactApiRequest struct {
@@ -22,11 +25,97 @@ type FactApiRequest struct {
 	TTL                   int                    `yaml:"ttl,omitempty" mapstructure:"ttl,omitempty" json:"ttl,omitempty"`
 }
 
+type FactStatusApiRequest struct {
+	Status            string `yaml:"status,omitempty" mapstructure:"status,omitempty" json:"status,omitempty"`
+	NotificationGroup string `yaml:"notification-group,omitempty" mapstructure:"notification-group,omitempty" json:"notification-group,omitempty"`
+	// ExpectedStatus makes the update conditional: the server applies it only if the current status of the fact is this one and
+	// responds with a 409 (or 412) otherwise. A pointer so that a fact without status can be expected: the empty value is sent.
+	ExpectedStatus *string `yaml:"expected-status,omitempty" mapstructure:"expected-status,omitempty" json:"expected-status,omitempty"`
+}
+
+// ExpectStatus the expected status of a conditional update, the empty one included.
+func ExpectStatus(status string) *string {
+	return &status
+}
+
+const (
+	FactsQueryDefaultPageSize = 100
+)
+
+// FactsQueryFilter optional criteria of a facts query. Zero values are not sent. Pages are addressed by offset and page size.
+type FactsQueryFilter struct {
+	CtxId    string    `yaml:"ctx-id,omitempty" mapstructure:"ctx-id,omitempty" json:"ctx-id,omitempty"`
+	TokenId  string    `yaml:"token-id,omitempty" mapstructure:"token-id,omitempty" json:"token-id,omitempty"`
+	Status   string    `yaml:"status,omitempty" mapstructure:"status,omitempty" json:"status,omitempty"`
+	From     time.Time `yaml:"from,omitempty" mapstructure:"from,omitempty" json:"from,omitempty"`
+	To       time.Time `yaml:"to,omitempty" mapstructure:"to,omitempty" json:"to,omitempty"`
+	Offset   int       `yaml:"offset,omitempty" mapstructure:"offset,omitempty" json:"offset,omitempty"`
+	PageSize int       `yaml:"page-size,omitempty" mapstructure:"page-size,omitempty" json:"page-size,omitempty"`
+	// Rid the _rid of the previous page: the following pages are asked for the same result set.
+	Rid string `yaml:"rid,omitempty" mapstructure:"rid,omitempty" json:"rid,omitempty"`
+}
+
+func (f *FactsQueryFilter) queryParams() []har.NameValuePair {
+	var qp []har.NameValuePair
+	if f.CtxId != "" {
+		qp = append(qp, har.NameValuePair{Name: "ctx-id", Value: token.WellFormTokenContextId(f.CtxId)})
+	}
+
+	if f.TokenId != "" {
+		qp = append(qp, har.NameValuePair{Name: "token-id", Value: token.WellFormTokenId(f.TokenId)})
+	}
+
+	if f.Status != "" {
+		qp = append(qp, har.NameValuePair{Name: "status", Value: f.Status})
+	}
+
+	if !f.From.IsZero() {
+		qp = append(qp, har.NameValuePair{Name: "from", Value: f.From.Format(time.RFC3339)})
+	}
+
+	if !f.To.IsZero() {
+		qp = append(qp, har.NameValuePair{Name: "to", Value: f.To.Format(time.RFC3339)})
+	}
+
+	if f.Offset > 0 {
+		qp = append(qp, har.NameValuePair{Name: "offset", Value: strconv.Itoa(f.Offset)})
+	}
+
+	if f.PageSize > 0 {
+		qp = append(qp, har.NameValuePair{Name: "page-size", Value: strconv.Itoa(f.PageSize)})
+	}
+
+	if f.Rid != "" {
+		qp = append(qp, har.NameValuePair{Name: "rid", Value: f.Rid})
+	}
+
+	return qp
+}
+
+// NextPage returns the filter for the page following the one of the response. The boolean is false if the response was the last page.
+func (f FactsQueryFilter) NextPage(resp *facts.FactsQueryResponse) (FactsQueryFilter, bool) {
+	if resp == nil || f.PageSize <= 0 || resp.RespCount < f.PageSize {
+		return f, false
+	}
+
+	f.Offset += resp.RespCount
+	f.Rid = resp.RespRid
+	return f, true
+}
+
 func (c *Client) QueryFacts(reqCtx ApiRequestContext, factsClass, factsGroup string) (*facts.FactsQueryResponse, error) {
+	return c.QueryFactsWithFilter(reqCtx, factsClass, factsGroup, nil)
+}
+
+func (c *Client) QueryFactsWithFilter(reqCtx ApiRequestContext, factsClass, factsGroup string, filter *FactsQueryFilter) (*facts.FactsQueryResponse, error) {
 	const semLogContext = "tpm-tokens-client::query-facts"
 	log.Trace().Msg(semLogContext)
 
-	ep := c.factsApiUrl(FactsQueryGroup, factsClass, factsGroup, "", nil)
+	var qp []har.NameValuePair
+	if filter != nil {
+		qp = filter.queryParams()
+	}
+	ep := c.factsApiUrl(FactsQueryGroup, factsClass, factsGroup, "", qp)
 
 	req, err := c.client.NewRequest(http.MethodGet, ep, nil, reqCtx.getHeaders(""), nil)
 	if err != nil {
@@ -51,6 +140,13 @@ func (c *Client) AddFact2Group(reqCtx ApiRequestContext, factsClass, factsGroup
 	const semLogContext = "tpm-tokens-client::add-fact-2-group"
 	log.Trace().Msg(semLogContext)
 
+	if fc, ok := facts.GetFactClass(factsClass); ok {
+		if err := fc.ValidateProperties(fact.Properties); err != nil {
+			log.Error().Err(err).Str("class", factsClass).Msg(semLogContext + " invalid fact properties")
+			return nil, NewBadRequestError(WithErrorMessage(err.Error()))
+		}
+	}
+
 	ep := c.factsApiUrl(FactAdd2Group, factsClass, factsGroup, "", nil)
 	ct := ContentTypeApplicationJson
 
@@ -78,6 +174,205 @@ func (c *Client) AddFact2Group(reqCtx ApiRequestContext, factsClass, factsGroup
 	return resp, err
 }
 
+// AddTypedFact2Group sets the properties of the fact from a value of the type registered for the class.
+func (c *Client) AddTypedFact2Group(reqCtx ApiRequestContext, factsClass, factsGroup string, fact *FactApiRequest, properties interface{}) (*facts.Fact, error) {
+	fc, ok := facts.GetFactClass(factsClass)
+	if !ok {
+		return nil, NewBadRequestError(WithErrorMessage(fmt.Sprintf("fact class %s not registered", factsClass)))
+	}
+
+	m, err := fc.EncodeProperties(properties)
+	if err != nil {
+		return nil, NewBadRequestError(WithErrorMessage(err.Error()))
+	}
+
+	fact.Properties = m
+	return c.AddFact2Group(reqCtx, factsClass, factsGroup, fact)
+}
+
+// QueryTypedFacts queries the facts of a registered class and decodes their properties into the class type. Facts whose properties
+// do not conform to the class are left out and reported in the error, together with the conforming ones.
+func (c *Client) QueryTypedFacts(reqCtx ApiRequestContext, factsClass, factsGroup string, filter *FactsQueryFilter) ([]facts.TypedFact, error) {
+	const semLogContext = "tpm-tokens-client::query-typed-facts"
+
+	fc, ok := facts.GetFactClass(factsClass)
+	if !ok {
+		return nil, NewBadRequestError(WithErrorMessage(fmt.Sprintf("fact class %s not registered", factsClass)))
+	}
+
+	resp, err := c.QueryFactsWithFilter(reqCtx, factsClass, factsGroup, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var tfs []facts.TypedFact
+	var errs []error
+	for i := range resp.Documents {
+		tf, err := fc.DecodeFact(&resp.Documents[i])
+		if err != nil {
+			log.Error().Err(err).Str("class", factsClass).Msg(semLogContext + " malformed fact")
+			errs = append(errs, err)
+			continue
+		}
+
+		tfs = append(tfs, tf)
+	}
+
+	return tfs, errors.Join(errs...)
+}
+
+// QueryAllFacts walks all the pages of the query. If the filter has no page size the default one is used. The walk stops at the first
+// page that adds no new fact, as happens with servers not honoring the offset.
+func (c *Client) QueryAllFacts(reqCtx ApiRequestContext, factsClass, factsGroup string, filter FactsQueryFilter) ([]facts.Fact, error) {
+	const semLogContext = "tpm-tokens-client::query-all-facts"
+
+	if filter.PageSize <= 0 {
+		filter.PageSize = FactsQueryDefaultPageSize
+	}
+
+	var fs []facts.Fact
+	seen := make(map[string]struct{})
+	for {
+		resp, err := c.QueryFactsWithFilter(reqCtx, factsClass, factsGroup, &filter)
+		if err != nil {
+			return nil, err
+		}
+
+		added := 0
+		for _, f := range resp.Documents {
+			if _, ok := seen[f.Id]; ok {
+				continue
+			}
+
+			seen[f.Id] = struct{}{}
+			fs = append(fs, f)
+			added++
+		}
+
+		var more bool
+		filter, more = filter.NextPage(resp)
+		if !more {
+			break
+		}
+
+		if added == 0 {
+			log.Warn().Int("offset", filter.Offset).Str("rid", resp.RespRid).Msg(semLogContext + " page without new facts... stopping")
+			break
+		}
+
+		log.Trace().Int("offset", filter.Offset).Str("rid", filter.Rid).Msg(semLogContext + " next page")
+	}
+
+	return fs, nil
+}
+
+func (c *Client) GetFact(reqCtx ApiRequestContext, factsClass, factsGroup, factId string) (*facts.Fact, error) {
+	const semLogContext = "tpm-tokens-client::get-fact"
+	log.Trace().Msg(semLogContext)
+
+	ep := c.factsApiUrl(FactGet, factsClass, factsGroup, factId, nil)
+
+	req, err := c.client.NewRequest(http.MethodGet, ep, nil, reqCtx.getHeaders(""), nil)
+	if err != nil {
+		return nil, NewBadRequestError(WithErrorMessage(err.Error()))
+	}
+
+	harEntry, err := c.client.Execute(req,
+		restclient.ExecutionWithOpName("client-get-fact"),
+		restclient.ExecutionWithRequestId(reqCtx.RequestId),
+		restclient.ExecutionWithSpan(reqCtx.Span),
+		restclient.ExecutionWithHarSpan(reqCtx.HarSpan))
+	// c.harEntries = append(c.harEntries, harEntry)
+	if err != nil {
+		return nil, NewExecutableServerError(WithErrorMessage(err.Error()))
+	}
+
+	resp, err := DeserializeFactContentResponse(harEntry)
+	return resp, err
+}
+
+func (c *Client) DeleteFact(reqCtx ApiRequestContext, factsClass, factsGroup, factId string) (bool, error) {
+	const semLogContext = "tpm-tokens-client::delete-fact"
+	log.Trace().Msg(semLogContext)
+
+	ep := c.factsApiUrl(FactDelete, factsClass, factsGroup, factId, nil)
+
+	req, err := c.client.NewRequest(http.MethodDelete, ep, nil, reqCtx.getHeaders(""), nil)
+	if err != nil {
+		return false, NewBadRequestError(WithErrorMessage(err.Error()))
+	}
+
+	harEntry, err := c.client.Execute(req,
+		restclient.ExecutionWithOpName("client-delete-fact"),
+		restclient.ExecutionWithRequestId(reqCtx.RequestId),
+		restclient.ExecutionWithSpan(reqCtx.Span),
+		restclient.ExecutionWithHarSpan(reqCtx.HarSpan))
+	// c.harEntries = append(c.harEntries, harEntry)
+	if err != nil {
+		return false, NewExecutableServerError(WithErrorMessage(err.Error()))
+	}
+
+	resp, err := DeserializeApiResponse(harEntry)
+	if err != nil {
+		return false, err
+	}
+
+	rc := false
+	switch resp.StatusCode {
+	case http.StatusOK:
+		rc = true
+	case http.StatusNotFound:
+	default:
+		// Return an error if not ok or not entity not found.
+		err = resp
+	}
+
+	return rc, err
+}
+
+// UpdateFactStatus sets the status and, if not empty, the notification group of the fact. With an expected status the update is a
+// compare and set: a fact in another status is reported as a conflict (see IsFactStatusConflict).
+func (c *Client) UpdateFactStatus(reqCtx ApiRequestContext, factsClass, factsGroup, factId string, status *FactStatusApiRequest) (*facts.Fact, error) {
+	const semLogContext = "tpm-tokens-client::update-fact-status"
+	log.Trace().Msg(semLogContext)
+
+	ep := c.factsApiUrl(FactStatusPut, factsClass, factsGroup, factId, nil)
+	ct := ContentTypeApplicationJson
+
+	b, err := json.Marshal(status)
+	if err != nil {
+		return nil, NewBadRequestError(WithErrorMessage(err.Error()))
+	}
+
+	req, err := c.client.NewRequest(http.MethodPut, ep, b, reqCtx.getHeaders(ct), nil)
+	if err != nil {
+		return nil, NewBadRequestError(WithErrorMessage(err.Error()))
+	}
+
+	harEntry, err := c.client.Execute(req,
+		restclient.ExecutionWithOpName("client-update-fact-status"),
+		restclient.ExecutionWithRequestId(reqCtx.RequestId),
+		restclient.ExecutionWithSpan(reqCtx.Span),
+		restclient.ExecutionWithHarSpan(reqCtx.HarSpan))
+	// c.harEntries = append(c.harEntries, harEntry)
+	if err != nil {
+		return nil, NewExecutableServerError(WithErrorMessage(err.Error()))
+	}
+
+	resp, err := DeserializeFactContentResponse(harEntry)
+	return resp, err
+}
+
+// IsFactStatusConflict the conditional update of the fact status failed because the fact was not in the expected status.
+func IsFactStatusConflict(err error) bool {
+	var resp *ApiResponse
+	if !errors.As(err, &resp) {
+		return false
+	}
+
+	return resp.StatusCode == http.StatusConflict || resp.StatusCode == http.StatusPreconditionFailed
+}
+
 func (c *Client) factsApiUrl(apiPath string, factsClass, factGroup, factId string, qParams []har.NameValuePair) string {
 	var sb = strings.Builder{}
 	sb.WriteString(c.host.Scheme)
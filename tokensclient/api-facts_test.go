@@ -0,0 +1,94 @@
+package tokensclient_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/facts"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestQueryAllFacts(t *testing.T) {
+
+	var mu sync.Mutex
+	var rids []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		rids = append(rids, r.URL.Query().Get("rid"))
+		mu.Unlock()
+
+		// 5 facts in pages of 2; the "stuck" group ignores the offset and returns always the first page.
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		if strings.HasSuffix(strings.ToLower(r.URL.Path), "/stuck") {
+			offset = 0
+		}
+
+		resp := facts.FactsQueryResponse{RespRid: "rid-1"}
+		for i := offset; i < offset+2 && i < 5; i++ {
+			resp.Documents = append(resp.Documents, facts.Fact{Id: fmt.Sprintf("F%d", i)})
+		}
+		resp.RespCount = len(resp.Documents)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&resp)
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+	cli, err := tokensclient.NewTokensApiClient(&tokensclient.Config{Host: tokensclient.HostInfo{Scheme: "http", HostName: u.Hostname(), Port: port}})
+	require.NoError(t, err)
+
+	fs, err := cli.QueryAllFacts(tokensclient.NewApiRequestContext(), "cls", "grp", tokensclient.FactsQueryFilter{PageSize: 2})
+	require.NoError(t, err)
+	require.Len(t, fs, 5)
+	require.Equal(t, []string{"", "rid-1", "rid-1"}, rids)
+
+	fs, err = cli.QueryAllFacts(tokensclient.NewApiRequestContext(), "cls", "stuck", tokensclient.FactsQueryFilter{PageSize: 2})
+	require.NoError(t, err)
+	require.Len(t, fs, 2)
+}
+
+type purchase struct {
+	Amount float64 `json:"amount"`
+}
+
+func TestQueryTypedFacts(t *testing.T) {
+
+	_, err := facts.RegisterFactClass("purchase", &purchase{}, []byte(`{"type": "object", "required": ["amount"], "properties": {"amount": {"type": "number", "minimum": 0}}}`))
+	require.NoError(t, err)
+	defer facts.UnregisterFactClass("purchase")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := facts.FactsQueryResponse{Documents: []facts.Fact{
+			{Id: "F1", Class: "purchase", Properties: map[string]interface{}{"amount": 10}},
+			{Id: "F2", Class: "purchase", Properties: map[string]interface{}{"amount": "ten"}},
+		}}
+		resp.RespCount = len(resp.Documents)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&resp)
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+	cli, err := tokensclient.NewTokensApiClient(&tokensclient.Config{Host: tokensclient.HostInfo{Scheme: "http", HostName: u.Hostname(), Port: port}})
+	require.NoError(t, err)
+
+	tfs, err := cli.QueryTypedFacts(tokensclient.NewApiRequestContext(), "purchase", "grp", nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "F2")
+	require.Len(t, tfs, 1)
+	require.Equal(t, 10.0, tfs[0].Value.(*purchase).Amount)
+
+	_, err = cli.QueryTypedFacts(tokensclient.NewApiRequestContext(), "unknown", "grp", nil)
+	require.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package tokensclient
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-archive/har"
 	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-client/restclient"
@@ -8,12 +9,19 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
+// TimerApiRequest the body of the partial update of a timer: fields left empty are kept as they are.
+type TimerApiRequest struct {
+	Expires string `yaml:"expires,omitempty" mapstructure:"expires,omitempty" json:"expires,omitempty"`
+	Status  string `yaml:"status,omitempty" mapstructure:"status,omitempty" json:"status,omitempty"`
+}
+
 func (c *Client) CreateTimers(reqCtx ApiRequestContext, ctxId string, tokId string) ([]token.Timer, error) {
 	const semLogContext = "tpm-tokens-client::post-create-timers"
 
-	ep := c.timerApiUrl(TokenTimerCreate, ctxId, tokId, nil)
+	ep := c.timerApiUrl(TokenTimerCreate, ctxId, tokId, "", nil)
 
 	req, err := c.client.NewRequest(http.MethodPost, ep, nil, reqCtx.getHeaders(""), nil)
 	if err != nil {
@@ -38,7 +46,7 @@ func (c *Client) CreateTimers(reqCtx ApiRequestContext, ctxId string, tokId stri
 func (c *Client) DeleteTimers(reqCtx ApiRequestContext, ctxId string, tokId string) (*ApiResponse, error) {
 	const semLogContext = "tpm-tokens-client::post-delete-timers"
 
-	ep := c.timerApiUrl(TokenTimersDelete, ctxId, tokId, nil)
+	ep := c.timerApiUrl(TokenTimersDelete, ctxId, tokId, "", nil)
 
 	req, err := c.client.NewRequest(http.MethodDelete, ep, nil, reqCtx.getHeaders(""), nil)
 	if err != nil {
@@ -60,7 +68,146 @@ func (c *Client) DeleteTimers(reqCtx ApiRequestContext, ctxId string, tokId stri
 	return resp, err
 }
 
-func (c *Client) timerApiUrl(apiPath string, ctxId string, tokenId string, qParams []har.NameValuePair) string {
+// QueryTimers lists the timers of a token or, if tokId is empty, of the whole context. Statuses, if any, restrict the result
+// (StatusTimerActive, StatusTimerProcessed, StatusTimerFailed).
+func (c *Client) QueryTimers(reqCtx ApiRequestContext, ctxId string, tokId string, statuses ...string) ([]token.Timer, error) {
+	const semLogContext = "tpm-tokens-client::query-timers"
+
+	var qp []har.NameValuePair
+	for _, st := range statuses {
+		qp = append(qp, har.NameValuePair{Name: "status", Value: st})
+	}
+
+	apiPath := TokenTimersQuery
+	if tokId == "" {
+		apiPath = ContextTimersQuery
+	}
+	ep := c.timerApiUrl(apiPath, ctxId, tokId, "", qp)
+
+	req, err := c.client.NewRequest(http.MethodGet, ep, nil, reqCtx.getHeaders(""), nil)
+	if err != nil {
+		return nil, NewBadRequestError(WithErrorMessage(err.Error()))
+	}
+
+	harEntry, err := c.client.Execute(req,
+		restclient.ExecutionWithOpName("client-query-timers"),
+		restclient.ExecutionWithRequestId(reqCtx.RequestId),
+		restclient.ExecutionWithSpan(reqCtx.Span),
+		restclient.ExecutionWithHarSpan(reqCtx.HarSpan))
+	// c.harEntries = append(c.harEntries, harEntry)
+	if err != nil {
+		return nil, NewExecutableServerError(WithErrorMessage(err.Error()))
+	}
+
+	resp, err := DeserializeTokenTimersResponseBody(harEntry)
+	return resp, err
+}
+
+func (c *Client) GetTimer(reqCtx ApiRequestContext, ctxId string, tokId string, timerId string) (*token.Timer, error) {
+	const semLogContext = "tpm-tokens-client::get-timer"
+
+	ep := c.timerApiUrl(TokenTimerGet, ctxId, tokId, timerId, nil)
+
+	req, err := c.client.NewRequest(http.MethodGet, ep, nil, reqCtx.getHeaders(""), nil)
+	if err != nil {
+		return nil, NewBadRequestError(WithErrorMessage(err.Error()))
+	}
+
+	harEntry, err := c.client.Execute(req,
+		restclient.ExecutionWithOpName("client-get-timer"),
+		restclient.ExecutionWithRequestId(reqCtx.RequestId),
+		restclient.ExecutionWithSpan(reqCtx.Span),
+		restclient.ExecutionWithHarSpan(reqCtx.HarSpan))
+	// c.harEntries = append(c.harEntries, harEntry)
+	if err != nil {
+		return nil, NewExecutableServerError(WithErrorMessage(err.Error()))
+	}
+
+	resp, err := DeserializeTokenTimerResponseBody(harEntry)
+	return resp, err
+}
+
+// DeleteTimer deletes a single timer leaving the other timers of the token in place.
+func (c *Client) DeleteTimer(reqCtx ApiRequestContext, ctxId string, tokId string, timerId string) (bool, error) {
+	const semLogContext = "tpm-tokens-client::delete-timer"
+
+	ep := c.timerApiUrl(TokenTimerDelete, ctxId, tokId, timerId, nil)
+
+	req, err := c.client.NewRequest(http.MethodDelete, ep, nil, reqCtx.getHeaders(""), nil)
+	if err != nil {
+		return false, NewBadRequestError(WithErrorMessage(err.Error()))
+	}
+
+	harEntry, err := c.client.Execute(req,
+		restclient.ExecutionWithOpName("client-delete-timer"),
+		restclient.ExecutionWithRequestId(reqCtx.RequestId),
+		restclient.ExecutionWithSpan(reqCtx.Span),
+		restclient.ExecutionWithHarSpan(reqCtx.HarSpan))
+	// c.harEntries = append(c.harEntries, harEntry)
+	if err != nil {
+		return false, NewExecutableServerError(WithErrorMessage(err.Error()))
+	}
+
+	resp, err := DeserializeApiResponse(harEntry)
+	if err != nil {
+		return false, err
+	}
+
+	rc := false
+	switch resp.StatusCode {
+	case http.StatusOK:
+		rc = true
+	case http.StatusNotFound:
+	default:
+		// Return an error if not ok or not entity not found.
+		err = resp
+	}
+
+	return rc, err
+}
+
+// RescheduleTimer moves the expiration of the timer.
+func (c *Client) RescheduleTimer(reqCtx ApiRequestContext, ctxId string, tokId string, timerId string, expires time.Time) (*token.Timer, error) {
+	return c.updateTimer(reqCtx, ctxId, tokId, timerId, &TimerApiRequest{Expires: expires.Format(time.RFC3339)}, "client-reschedule-timer")
+}
+
+// UpdateTimerStatus sets the status of the timer (StatusTimerActive, StatusTimerProcessed, StatusTimerFailed).
+func (c *Client) UpdateTimerStatus(reqCtx ApiRequestContext, ctxId string, tokId string, timerId string, status string) (*token.Timer, error) {
+	return c.updateTimer(reqCtx, ctxId, tokId, timerId, &TimerApiRequest{Status: status}, "client-update-timer-status")
+}
+
+// updateTimer patches the timer with the non empty fields of the request.
+func (c *Client) updateTimer(reqCtx ApiRequestContext, ctxId string, tokId string, timerId string, timerReq *TimerApiRequest, opName string) (*token.Timer, error) {
+	const semLogContext = "tpm-tokens-client::update-timer"
+
+	ep := c.timerApiUrl(TokenTimerPatch, ctxId, tokId, timerId, nil)
+	ct := ContentTypeApplicationJson
+
+	b, err := json.Marshal(timerReq)
+	if err != nil {
+		return nil, NewBadRequestError(WithErrorMessage(err.Error()))
+	}
+
+	req, err := c.client.NewRequest(http.MethodPatch, ep, b, reqCtx.getHeaders(ct), nil)
+	if err != nil {
+		return nil, NewBadRequestError(WithErrorMessage(err.Error()))
+	}
+
+	harEntry, err := c.client.Execute(req,
+		restclient.ExecutionWithOpName(opName),
+		restclient.ExecutionWithRequestId(reqCtx.RequestId),
+		restclient.ExecutionWithSpan(reqCtx.Span),
+		restclient.ExecutionWithHarSpan(reqCtx.HarSpan))
+	// c.harEntries = append(c.harEntries, harEntry)
+	if err != nil {
+		return nil, NewExecutableServerError(WithErrorMessage(err.Error()))
+	}
+
+	resp, err := DeserializeTokenTimerResponseBody(harEntry)
+	return resp, err
+}
+
+func (c *Client) timerApiUrl(apiPath string, ctxId string, tokenId string, timerId string, qParams []har.NameValuePair) string {
 	var sb = strings.Builder{}
 	sb.WriteString(c.host.Scheme)
 	sb.WriteString("://")
@@ -70,6 +217,7 @@ func (c *Client) timerApiUrl(apiPath string, ctxId string, tokenId string, qPara
 
 	apiPath = strings.Replace(apiPath, TokenContextIdPathPlaceHolder, token.WellFormTokenContextId(ctxId), 1)
 	apiPath = strings.Replace(apiPath, TokenIdPathPlaceHolder, token.WellFormTokenId(tokenId), 1)
+	apiPath = strings.Replace(apiPath, TimerIdPathPlaceHolder, token.WellFormTimerId(timerId), 1)
 	sb.WriteString(apiPath)
 
 	if len(qParams) > 0 {
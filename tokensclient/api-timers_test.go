@@ -0,0 +1,64 @@
+package tokensclient_test
+
+import (
+	"encoding/json"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUpdateTimer(t *testing.T) {
+
+	var mu sync.Mutex
+	var methods []string
+	var bodies []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		var m map[string]interface{}
+		_ = json.Unmarshal(b, &m)
+		mu.Lock()
+		methods = append(methods, r.Method)
+		bodies = append(bodies, m)
+		mu.Unlock()
+
+		// The timer keeps the fields not sent in the request.
+		tm := token.Timer{Id: "TM1", Status: token.StatusTimerActive, Expires: "2023-04-30T10:00:00Z", TTL: 60}
+		if v, ok := m["status"].(string); ok {
+			tm.Status = v
+		}
+		if v, ok := m["expires"].(string); ok {
+			tm.Expires = v
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&tm)
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+	cli, err := tokensclient.NewTokensApiClient(&tokensclient.Config{Host: tokensclient.HostInfo{Scheme: "http", HostName: u.Hostname(), Port: port}})
+	require.NoError(t, err)
+
+	tm, err := cli.RescheduleTimer(tokensclient.NewApiRequestContext(), "ctx", "tok", "TM1", time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, token.StatusTimerActive, tm.Status)
+	require.Equal(t, 60, tm.TTL)
+
+	tm, err = cli.UpdateTimerStatus(tokensclient.NewApiRequestContext(), "ctx", "tok", "TM1", token.StatusTimerProcessed)
+	require.NoError(t, err)
+	require.Equal(t, "2023-04-30T10:00:00Z", tm.Expires)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []string{http.MethodPatch, http.MethodPatch}, methods)
+	require.Equal(t, []map[string]interface{}{{"expires": "2023-05-01T10:00:00Z"}, {"status": token.StatusTimerProcessed}}, bodies)
+}
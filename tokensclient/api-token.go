@@ -229,6 +229,20 @@ func (c *Client) tokenNext(reqCtx ApiRequestContext, ctxId string, tokId string,
 func (c *Client) TokenCheck(reqCtx ApiRequestContext, ctxId string, tokId string, tokenRequest *TokenApiRequest, ct string) (*token.Token, error) {
 	const semLogContext = "tpm-tokens-client::token-check"
 
+	if c.tokIdValidator != nil {
+		id, err := c.tokIdValidator.ValidateTokenId(ctxId, tokId)
+		if err != nil {
+			log.Warn().Err(err).Str("ctx-id", ctxId).Str("token-id", tokId).Msg(semLogContext + " token id rejected")
+			return nil, NewBadRequestError(WithCode(InvalidTokenIdErrorCode), WithErrorMessage(err.Error()))
+		}
+
+		// the normalized id is the one checked: the request carries it as well if it carried the id as typed.
+		if tokenRequest.TokenId == tokId {
+			tokenRequest.TokenId = id
+		}
+		tokId = id
+	}
+
 	ep := c.tokenApiUrl(TokenCheck, ctxId, tokId, "", nil)
 
 	if ct == "" {
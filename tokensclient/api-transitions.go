@@ -0,0 +1,92 @@
+package tokensclient
+
+import (
+	"encoding/json"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-client/restclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/rs/zerolog/log"
+	"net/http"
+)
+
+// QueryAvailableTransitions asks the server the transitions available from the current state of the token given the supplied input.
+// Without a token id the transitions are the ones available to a new token, out of the start state of the context.
+func (c *Client) QueryAvailableTransitions(reqCtx ApiRequestContext, ctxId string, tokId string, input map[string]interface{}) (*token.AvailableTransitions, error) {
+	ep := c.tokenApiUrl(TokenTransitions, ctxId, tokId, "", nil)
+	if tokId == "" {
+		ep = c.tokenApiUrl(ContextTransitions, ctxId, "", "", nil)
+	}
+	ct := ContentTypeApplicationJson
+
+	b, err := json.Marshal(&TokenApiRequest{TokenId: token.WellFormTokenId(tokId), CustomData: input})
+	if err != nil {
+		return nil, NewBadRequestError(WithErrorMessage(err.Error()))
+	}
+
+	req, err := c.client.NewRequest(http.MethodPost, ep, b, reqCtx.getHeaders(ct), nil)
+	if err != nil {
+		return nil, NewBadRequestError(WithErrorMessage(err.Error()))
+	}
+
+	harEntry, err := c.client.Execute(req,
+		restclient.ExecutionWithOpName("client-token-transitions"),
+		restclient.ExecutionWithRequestId(reqCtx.RequestId),
+		restclient.ExecutionWithLraId(reqCtx.LRAId),
+		restclient.ExecutionWithSpan(reqCtx.Span),
+		restclient.ExecutionWithHarSpan(reqCtx.HarSpan))
+	// c.harEntries = append(c.harEntries, harEntry)
+	if err != nil {
+		return nil, NewExecutableServerError(WithErrorMessage(err.Error()))
+	}
+
+	resp, err := DeserializeAvailableTransitionsResponseBody(harEntry)
+	return resp, err
+}
+
+// AvailableTransitions queries the server and, if the server does not support the query, falls back to the local evaluation
+// against the token context. tokCtx and tok can be provided to save the round trips needed to fetch them in the fallback case.
+// An empty tokId queries the transitions of a new token.
+func (c *Client) AvailableTransitions(reqCtx ApiRequestContext, ctxId string, tokId string, tokCtx *token.TokenContext, tok *token.Token, input map[string]interface{}) (*token.AvailableTransitions, error) {
+	const semLogContext = "tpm-tokens-client::available-transitions"
+
+	ats, err := c.QueryAvailableTransitions(reqCtx, ctxId, tokId, input)
+	if err == nil {
+		return ats, nil
+	}
+
+	if !isQueryUnsupported(err) {
+		return nil, err
+	}
+
+	log.Warn().Err(err).Str("ctx-id", ctxId).Str("token-id", tokId).Msg(semLogContext + " server query not available... reverting to local evaluation")
+	if tokCtx == nil {
+		tokCtx, err = c.GetTokenContextById(reqCtx, ctxId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if tok == nil && tokId != "" {
+		tok, err = c.GetToken(reqCtx, ctxId, tokId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tokCtx.AvailableTransitions(tok, input)
+}
+
+// isQueryUnsupported the server responded without a business error code, usually with an empty or plain text body: the endpoint
+// is unknown or not implemented.
+func isQueryUnsupported(err error) bool {
+	resp, ok := err.(*ApiResponse)
+	if !ok || resp.ErrCode != "" {
+		return false
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,97 @@
+package tokensclient_test
+
+import (
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-client/restclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+var transitionsTestContext = token.TokenContext{
+	Id: "CTX",
+	StateMachine: token.StateMachine{
+		States: []token.StateDefinition{
+			{
+				Code:           token.StartEndState,
+				OutTransitions: []token.Transition{{Name: "genera", To: "generato"}},
+			},
+			{
+				Code: "generato",
+				OutTransitions: []token.Transition{
+					{Name: "uso-ok", To: "bruciato", Rules: []token.Rule{{Expression: "\"{$.result}\" == \"OK\""}}},
+					{Name: "uso-ko", To: "generato", Rules: []token.Rule{{Expression: "\"{$.result}\" == \"KO\""}}},
+				},
+			},
+		},
+	},
+}
+
+func TestAvailableTransitionsFallback(t *testing.T) {
+
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.Header.Get("X-Mode") {
+		case "plain":
+			http.NotFound(w, r)
+		case "business":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error-code": "token-not-found", "text": "token not found"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+	host := tokensclient.HostInfo{Scheme: "http", HostName: u.Hostname(), Port: port}
+
+	tok := token.Token{Id: "TOK1", Events: []token.Event{{State: token.State{Code: "generato"}}}}
+	input := map[string]interface{}{"result": "OK"}
+
+	cli, err := tokensclient.NewTokensApiClient(&tokensclient.Config{Host: host})
+	require.NoError(t, err)
+
+	// bare 404: the route is unknown and the transitions are evaluated locally.
+	_, err = cli.QueryAvailableTransitions(tokensclient.NewApiRequestContext(), "CTX", "TOK1", input)
+	require.Error(t, err)
+	require.Equal(t, http.StatusNotFound, err.(*tokensclient.ApiResponse).StatusCode)
+
+	ats, err := cli.AvailableTransitions(tokensclient.NewApiRequestContext(), "CTX", "TOK1", &transitionsTestContext, &tok, input)
+	require.NoError(t, err)
+	require.Len(t, ats.Enabled(), 1)
+	require.Equal(t, "uso-ok", ats.Enabled()[0].Name)
+
+	// plain text 404 as served by default muxes.
+	cli, err = tokensclient.NewTokensApiClient(&tokensclient.Config{Config: restclient.Config{Headers: []restclient.Header{{Name: "X-Mode", Value: "plain"}}}, Host: host})
+	require.NoError(t, err)
+	ats, err = cli.AvailableTransitions(tokensclient.NewApiRequestContext(), "CTX", "TOK1", &transitionsTestContext, &tok, input)
+	require.NoError(t, err)
+	require.Equal(t, "generato", ats.State)
+
+	// business errors are returned as they are.
+	cli, err = tokensclient.NewTokensApiClient(&tokensclient.Config{Config: restclient.Config{Headers: []restclient.Header{{Name: "X-Mode", Value: "business"}}}, Host: host})
+	require.NoError(t, err)
+	_, err = cli.AvailableTransitions(tokensclient.NewApiRequestContext(), "CTX", "TOK1", &transitionsTestContext, &tok, input)
+	require.Equal(t, "token-not-found", tokensclient.ErrorCode(err))
+
+	// no token id: the transitions of a new token are queried on the context and evaluated out of the start state.
+	cli, err = tokensclient.NewTokensApiClient(&tokensclient.Config{Host: host})
+	require.NoError(t, err)
+	paths = nil
+	ats, err = cli.AvailableTransitions(tokensclient.NewApiRequestContext(), "CTX", "", &transitionsTestContext, nil, input)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/api/v1/token-contexts/CTX/transitions"}, paths)
+	require.Equal(t, token.StartEndState, ats.State)
+	require.Len(t, ats.Enabled(), 1)
+	require.Equal(t, "genera", ats.Enabled()[0].Name)
+}
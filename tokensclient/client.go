@@ -1,6 +1,7 @@
 package tokensclient
 
 import (
+	"encoding/json"
 	"errors"
 	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-archive/har"
 	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-http-client/restclient"
@@ -10,6 +11,7 @@ import (
 	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
 	"github.com/rs/zerolog/log"
 	"net/http"
+	"strings"
 )
 
 const (
@@ -21,12 +23,24 @@ const (
 	ContentTypeApplicationJson = "application/json"
 )
 
+// TokenIdValidator validates token ids client side so that mistyped ones are rejected without a server round trip. The id returned is
+// the normalized one, i.e. without blanks, the one sent to the server.
+type TokenIdValidator interface {
+	ValidateTokenId(ctxId string, tokenId string) (string, error)
+}
+
 type Client struct {
-	host   HostInfo
-	client *restclient.Client
+	host           HostInfo
+	client         *restclient.Client
+	tokIdValidator TokenIdValidator
 	// harEntries []*har.Entry
 }
 
+// SetTokenIdValidator sets the validator applied to the token ids of the check operations. A nil validator disables the check.
+func (c *Client) SetTokenIdValidator(v TokenIdValidator) {
+	c.tokIdValidator = v
+}
+
 func (c *Client) Close() {
 	c.client.Close()
 }
@@ -187,6 +201,54 @@ func DeserializeTokenResponseBody(resp *har.Entry) (*token.Token, error) {
 	return resultObj, nil
 }
 
+func DeserializeAvailableTransitionsResponseBody(resp *har.Entry) (*token.AvailableTransitions, error) {
+
+	const semLogContext = "tokens-api-client::deserialize-available-transitions-response"
+	if resp == nil || resp.Response == nil {
+		err := errors.New("cannot deserialize null response")
+		log.Error().Err(err).Msg(semLogContext)
+		return nil, NewExecutableServerError(WithErrorMessage(err.Error()))
+	}
+
+	var data []byte
+	if resp.Response.Content != nil {
+		data = resp.Response.Content.Data
+	}
+
+	var resultObj *token.AvailableTransitions
+	var err error
+	switch resp.Response.Status {
+	case http.StatusOK:
+		if len(data) == 0 {
+			err = errors.New("cannot deserialize null response")
+			log.Error().Err(err).Msg(semLogContext)
+			return nil, NewExecutableServerError(WithErrorMessage(err.Error()))
+		}
+
+		resultObj, err = token.DeserializeAvailableTransitions(data)
+		if err != nil {
+			return nil, NewExecutableServerError(WithErrorMessage(err.Error()))
+		}
+
+	default:
+		// servers not exposing the endpoint answer with an empty or plain text body: the status code is what matters.
+		var apiResponse ApiResponse
+		if len(data) > 0 && json.Valid(data) {
+			apiResponse, err = DeserApiResponseFromJson(data)
+			if err != nil {
+				return nil, NewExecutableServerError(WithErrorMessage(err.Error()))
+			}
+		} else {
+			apiResponse.Text = strings.TrimSpace(string(data))
+		}
+		apiResponse.StatusCode = resp.Response.Status
+		err = &apiResponse
+		return nil, err
+	}
+
+	return resultObj, nil
+}
+
 func DeserializeTokenTimerResponseBody(resp *har.Entry) (*token.Timer, error) {
 
 	const semLogContext = "tokens-api-client::deserialize-token-timer-response"
@@ -350,6 +412,11 @@ func DeserializeQueryFactsContentResponse(resp *har.Entry) (*facts.FactsQueryRes
 func DeserializeFactContentResponse(resp *har.Entry) (*facts.Fact, error) {
 
 	const semLogContext = "tokens-api-client::deserialize-fact-response"
+	if resp != nil && resp.Response != nil && resp.Response.Status != http.StatusOK && (resp.Response.Content == nil || !json.Valid(resp.Response.Content.Data)) {
+		// conditional status updates may be refused with a bare 409/412: the status code is what matters.
+		return nil, &ApiResponse{StatusCode: resp.Response.Status}
+	}
+
 	if resp == nil || resp.Response == nil || resp.Response.Content == nil || resp.Response.Content.Data == nil {
 		err := errors.New("cannot deserialize null response")
 		log.Error().Err(err).Msg(semLogContext)
@@ -10,6 +10,7 @@ const (
 	FactClassPathPlaceHolder      = "{fact-class}"
 	FactGroupPathPlaceHolder      = "{fact-group}"
 	FactIdPathPlaceHolder         = "{fact-id}"
+	TimerIdPathPlaceHolder        = "{timer-id}"
 
 	TokenContextBasePath = "/api/v1/token-contexts"
 	TokenContextQuery    = TokenContextBasePath
@@ -28,7 +29,14 @@ const (
 	TokenRollback       = TokenBasePath + "/" + TokenIdPathPlaceHolder + "/rollback"
 	TokenTimerCreate    = TokenBasePath + "/" + TokenIdPathPlaceHolder + "/timers"
 	TokenTimersDelete   = TokenBasePath + "/" + TokenIdPathPlaceHolder + "/timers"
+	TokenTimersQuery    = TokenBasePath + "/" + TokenIdPathPlaceHolder + "/timers"
+	TokenTimerGet       = TokenBasePath + "/" + TokenIdPathPlaceHolder + "/timers/" + TimerIdPathPlaceHolder
+	TokenTimerDelete    = TokenBasePath + "/" + TokenIdPathPlaceHolder + "/timers/" + TimerIdPathPlaceHolder
+	TokenTimerPatch     = TokenBasePath + "/" + TokenIdPathPlaceHolder + "/timers/" + TimerIdPathPlaceHolder
+	ContextTimersQuery  = TokenContextBasePath + "/" + TokenContextIdPathPlaceHolder + "/timers"
+	ContextTransitions  = TokenContextBasePath + "/" + TokenContextIdPathPlaceHolder + "/transitions"
 	TokenTakeTransition = TokenBasePath + "/" + TokenIdPathPlaceHolder + "/take/" + TransitionNamePathPlaceHolder
+	TokenTransitions    = TokenBasePath + "/" + TokenIdPathPlaceHolder + "/transitions"
 
 	BearerBasePath                       = "/api/v1/bearers"
 	BearersByActorId                     = BearerBasePath + "/" + ActorIdPathPlaceHolder
@@ -47,6 +55,8 @@ const (
 	FactsQueryGroup  = ApiFactsBasePath + FactClassPathPlaceHolder + "/" + FactGroupPathPlaceHolder
 	FactGet          = ApiFactsBasePath + FactClassPathPlaceHolder + "/" + FactGroupPathPlaceHolder + "/" + FactIdPathPlaceHolder
 	FactAdd2Group    = ApiFactsBasePath + FactClassPathPlaceHolder + "/" + FactGroupPathPlaceHolder
+	FactDelete       = ApiFactsBasePath + FactClassPathPlaceHolder + "/" + FactGroupPathPlaceHolder + "/" + FactIdPathPlaceHolder
+	FactStatusPut    = ApiFactsBasePath + FactClassPathPlaceHolder + "/" + FactGroupPathPlaceHolder + "/" + FactIdPathPlaceHolder + "/status"
 )
 
 type HostInfo struct {
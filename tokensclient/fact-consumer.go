@@ -0,0 +1,314 @@
+package tokensclient
+
+import (
+	"errors"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-common/util"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/facts"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/rs/zerolog/log"
+	"sync"
+	"time"
+)
+
+const (
+	FactConsumerDefaultPollInterval  = 30 * time.Second
+	FactConsumerDefaultLeaseDuration = 5 * time.Minute
+	FactConsumerDefaultMaxAttempts   = 3
+	FactConsumerDefaultBackoff       = 10 * time.Second
+	FactConsumerDefaultMaxBackoff    = 10 * time.Minute
+
+	// FactConsumerAnyNotificationGroup key of the handler used for facts whose notification group has no specific handler.
+	FactConsumerAnyNotificationGroup = "*"
+)
+
+var (
+	ErrFactLeaseLost = errors.New("fact lease acquired by another consumer")
+	ErrFactNoHandler = errors.New("no handler registered for the fact notification group")
+)
+
+// FactsStore the facts operations needed by the consumer. Client implements it. Status updates carrying an expected status must
+// be applied atomically and refused with a conflict (see IsFactStatusConflict) if the fact is in another status.
+type FactsStore interface {
+	QueryFactsWithFilter(reqCtx ApiRequestContext, factsClass, factsGroup string, filter *FactsQueryFilter) (*facts.FactsQueryResponse, error)
+	UpdateFactStatus(reqCtx ApiRequestContext, factsClass, factsGroup, factId string, status *FactStatusApiRequest) (*facts.Fact, error)
+}
+
+type FactHandler func(reqCtx ApiRequestContext, f *facts.Fact) error
+
+type FactConsumerConfig struct {
+	Class         string        `yaml:"class,omitempty" mapstructure:"class,omitempty" json:"class,omitempty"`
+	Group         string        `yaml:"group,omitempty" mapstructure:"group,omitempty" json:"group,omitempty"`
+	Owner         string        `yaml:"owner,omitempty" mapstructure:"owner,omitempty" json:"owner,omitempty"`
+	PollInterval  time.Duration `yaml:"poll-interval,omitempty" mapstructure:"poll-interval,omitempty" json:"poll-interval,omitempty"`
+	LeaseDuration time.Duration `yaml:"lease-duration,omitempty" mapstructure:"lease-duration,omitempty" json:"lease-duration,omitempty"`
+	MaxAttempts   int           `yaml:"max-attempts,omitempty" mapstructure:"max-attempts,omitempty" json:"max-attempts,omitempty"`
+	Backoff       time.Duration `yaml:"backoff,omitempty" mapstructure:"backoff,omitempty" json:"backoff,omitempty"`
+	MaxBackoff    time.Duration `yaml:"max-backoff,omitempty" mapstructure:"max-backoff,omitempty" json:"max-backoff,omitempty"`
+	PageSize      int           `yaml:"page-size,omitempty" mapstructure:"page-size,omitempty" json:"page-size,omitempty"`
+}
+
+// FactConsumer periodically polls a class/group of facts and dispatches the pending ones to the handler registered for their notification group.
+// Before handling a fact the consumer stores a lease in its status with a conditional update on the status it has read: if another
+// replica changed it in the meanwhile the update is refused and the fact is skipped.
+// A handled fact is marked processed, a failed one is scheduled for retry with an exponential backoff and marked failed when attempts are exhausted.
+type FactConsumer struct {
+	cfg      FactConsumerConfig
+	store    FactsStore
+	handlers map[string]FactHandler
+	clock    token.Clock
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+}
+
+type FactConsumerOption func(fc *FactConsumer)
+
+// FactConsumerWithClock the clock leases and retries are computed with. The system clock by default.
+func FactConsumerWithClock(c token.Clock) FactConsumerOption {
+	return func(fc *FactConsumer) {
+		if c != nil {
+			fc.clock = c
+		}
+	}
+}
+
+func NewFactConsumer(store FactsStore, cfg FactConsumerConfig, opts ...FactConsumerOption) (*FactConsumer, error) {
+	const semLogContext = "fact-consumer::new"
+
+	if store == nil || cfg.Class == "" || cfg.Group == "" {
+		err := errors.New("fact consumer requires store, class and group")
+		log.Error().Err(err).Msg(semLogContext)
+		return nil, err
+	}
+
+	if cfg.Owner == "" {
+		cfg.Owner = util.NewObjectId().String()
+		log.Info().Str("owner", cfg.Owner).Msg(semLogContext + " owner not set... auto generated")
+	}
+
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = FactConsumerDefaultPollInterval
+	}
+
+	if cfg.LeaseDuration <= 0 {
+		cfg.LeaseDuration = FactConsumerDefaultLeaseDuration
+	}
+
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = FactConsumerDefaultMaxAttempts
+	}
+
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = FactConsumerDefaultBackoff
+	}
+
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = FactConsumerDefaultMaxBackoff
+	}
+
+	if cfg.PageSize <= 0 {
+		cfg.PageSize = FactsQueryDefaultPageSize
+	}
+
+	fc := &FactConsumer{cfg: cfg, store: store, handlers: make(map[string]FactHandler), clock: token.SystemClock}
+	for _, o := range opts {
+		o(fc)
+	}
+
+	return fc, nil
+}
+
+// Handle registers the handler of a notification group. Use FactConsumerAnyNotificationGroup for the fallback handler.
+func (fc *FactConsumer) Handle(notificationGroup string, h FactHandler) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.handlers[notificationGroup] = h
+}
+
+func (fc *FactConsumer) handler(notificationGroup string) (FactHandler, bool) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if h, ok := fc.handlers[notificationGroup]; ok {
+		return h, true
+	}
+
+	h, ok := fc.handlers[FactConsumerAnyNotificationGroup]
+	return h, ok
+}
+
+// Start polls the facts every PollInterval until Stop is called.
+func (fc *FactConsumer) Start(opts ...APIRequestContextOption) {
+	const semLogContext = "fact-consumer::start"
+
+	fc.mu.Lock()
+	if fc.quit != nil {
+		fc.mu.Unlock()
+		log.Warn().Msg(semLogContext + " consumer already started")
+		return
+	}
+	fc.quit = make(chan struct{})
+	quit := fc.quit
+	fc.mu.Unlock()
+
+	fc.wg.Add(1)
+	go func() {
+		defer fc.wg.Done()
+		ticker := time.NewTicker(fc.cfg.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			reqCtx := NewApiRequestContext(append([]APIRequestContextOption{ApiRequestWithAutoRequestId()}, opts...)...)
+			if _, err := fc.Poll(reqCtx); err != nil {
+				log.Error().Err(err).Str("class", fc.cfg.Class).Str("group", fc.cfg.Group).Msg(semLogContext + " poll error")
+			}
+
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (fc *FactConsumer) Stop() {
+	fc.mu.Lock()
+	quit := fc.quit
+	fc.quit = nil
+	fc.mu.Unlock()
+
+	if quit != nil {
+		close(quit)
+		fc.wg.Wait()
+	}
+}
+
+// Poll runs a single pass over the pending facts, page by page, and returns the number of facts handled successfully.
+func (fc *FactConsumer) Poll(reqCtx ApiRequestContext) (int, error) {
+	const semLogContext = "fact-consumer::poll"
+
+	filter := FactsQueryFilter{PageSize: fc.cfg.PageSize}
+	seen := make(map[string]struct{})
+	numHandled := 0
+	for {
+		resp, err := fc.store.QueryFactsWithFilter(reqCtx, fc.cfg.Class, fc.cfg.Group, &filter)
+		if err != nil {
+			return numHandled, err
+		}
+
+		added := 0
+		for i := range resp.Documents {
+			f := &resp.Documents[i]
+			if _, ok := seen[f.Id]; ok {
+				continue
+			}
+			seen[f.Id] = struct{}{}
+			added++
+
+			if !f.IsPendingAt(fc.clock.Now()) {
+				continue
+			}
+
+			err = fc.consume(reqCtx, f)
+			switch {
+			case err == nil:
+				numHandled++
+			case errors.Is(err, ErrFactLeaseLost):
+				log.Trace().Str("fact-id", f.Id).Msg(semLogContext + " fact owned by another consumer")
+			case errors.Is(err, ErrFactNoHandler):
+				log.Trace().Str("fact-id", f.Id).Str("notification-group", f.NotificationGroup).Msg(semLogContext + " no handler registered")
+			default:
+				log.Error().Err(err).Str("fact-id", f.Id).Msg(semLogContext + " fact not handled")
+			}
+		}
+
+		var more bool
+		filter, more = filter.NextPage(resp)
+		if !more || added == 0 {
+			break
+		}
+	}
+
+	return numHandled, nil
+}
+
+func (fc *FactConsumer) consume(reqCtx ApiRequestContext, f *facts.Fact) error {
+	const semLogContext = "fact-consumer::consume"
+
+	h, ok := fc.handler(f.NotificationGroup)
+	if !ok {
+		return ErrFactNoHandler
+	}
+
+	attempt := 1
+	if l, ok := f.Lease(); ok {
+		attempt = l.Attempt + 1
+	}
+
+	if err := fc.acquire(reqCtx, f, attempt); err != nil {
+		return err
+	}
+
+	herr := h(reqCtx, f)
+	if herr == nil {
+		return fc.release(reqCtx, f, facts.StatusFactProcessed)
+	}
+
+	status := facts.StatusFactFailed
+	if attempt < fc.cfg.MaxAttempts {
+		status = facts.FactLease{Until: fc.clock.Now().Add(fc.backoff(attempt)), Attempt: attempt}.Status()
+	}
+
+	log.Warn().Err(herr).Str("fact-id", f.Id).Int("attempt", attempt).Str("status", status).Msg(semLogContext + " handler error")
+	if err := fc.release(reqCtx, f, status); err != nil {
+		return err
+	}
+
+	return herr
+}
+
+// acquire stores the lease in the fact status provided the fact is still in the status read by the poll.
+func (fc *FactConsumer) acquire(reqCtx ApiRequestContext, f *facts.Fact, attempt int) error {
+	lease := facts.FactLease{Owner: fc.cfg.Owner, Until: fc.clock.Now().Add(fc.cfg.LeaseDuration), Attempt: attempt}
+	status := lease.Status()
+
+	_, err := fc.store.UpdateFactStatus(reqCtx, fc.cfg.Class, fc.cfg.Group, f.Id, &FactStatusApiRequest{Status: status, ExpectedStatus: ExpectStatus(f.Status)})
+	if err != nil {
+		if IsFactStatusConflict(err) {
+			return ErrFactLeaseLost
+		}
+		return err
+	}
+
+	f.Status = status
+	return nil
+}
+
+// release sets the outcome of the handling provided the lease is still the one of the consumer: an expired lease may have been
+// acquired by another consumer.
+func (fc *FactConsumer) release(reqCtx ApiRequestContext, f *facts.Fact, status string) error {
+	_, err := fc.store.UpdateFactStatus(reqCtx, fc.cfg.Class, fc.cfg.Group, f.Id, &FactStatusApiRequest{Status: status, ExpectedStatus: ExpectStatus(f.Status)})
+	if err != nil {
+		if IsFactStatusConflict(err) {
+			return ErrFactLeaseLost
+		}
+		return err
+	}
+
+	f.Status = status
+	return nil
+}
+
+func (fc *FactConsumer) backoff(attempt int) time.Duration {
+	d := fc.cfg.Backoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= fc.cfg.MaxBackoff {
+			return fc.cfg.MaxBackoff
+		}
+	}
+
+	return d
+}
@@ -0,0 +1,168 @@
+package tokensclient_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/facts"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memFactsStore applies the conditional status updates atomically, as the server does.
+type memFactsStore struct {
+	mu    sync.Mutex
+	facts map[string]*facts.Fact
+}
+
+func (s *memFactsStore) QueryFactsWithFilter(reqCtx tokensclient.ApiRequestContext, factsClass, factsGroup string, filter *tokensclient.FactsQueryFilter) (*facts.FactsQueryResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.facts))
+	for id := range s.facts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	resp := facts.FactsQueryResponse{}
+	for i := filter.Offset; i < len(ids) && (filter.PageSize <= 0 || i < filter.Offset+filter.PageSize); i++ {
+		resp.Documents = append(resp.Documents, *s.facts[ids[i]])
+	}
+	resp.RespCount = len(resp.Documents)
+	return &resp, nil
+}
+
+func (s *memFactsStore) UpdateFactStatus(reqCtx tokensclient.ApiRequestContext, factsClass, factsGroup, factId string, status *tokensclient.FactStatusApiRequest) (*facts.Fact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if status.ExpectedStatus != nil && s.facts[factId].Status != *status.ExpectedStatus {
+		return nil, tokensclient.NewExecutableError(tokensclient.WithErrorStatusCode(http.StatusConflict))
+	}
+
+	s.facts[factId].Status = status.Status
+	f := *s.facts[factId]
+	return &f, nil
+}
+
+func (s *memFactsStore) status(factId string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.facts[factId].Status
+}
+
+func TestFactConsumer(t *testing.T) {
+
+	clk := token.NewFakeClock(time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC))
+
+	store := &memFactsStore{facts: map[string]*facts.Fact{
+		"F1": {Id: "F1", Status: facts.StatusFactActive, NotificationGroup: "ok"},
+		"F2": {Id: "F2", Status: facts.StatusFactActive, NotificationGroup: "ko"},
+	}}
+
+	fc, err := tokensclient.NewFactConsumer(store, tokensclient.FactConsumerConfig{Class: "cls", Group: "grp", Owner: "w1", MaxAttempts: 2, Backoff: time.Minute}, tokensclient.FactConsumerWithClock(clk))
+	require.NoError(t, err)
+
+	fc.Handle("ok", func(reqCtx tokensclient.ApiRequestContext, f *facts.Fact) error { return nil })
+	fc.Handle(tokensclient.FactConsumerAnyNotificationGroup, func(reqCtx tokensclient.ApiRequestContext, f *facts.Fact) error {
+		return errors.New("downstream unavailable")
+	})
+
+	reqCtx := tokensclient.NewApiRequestContext()
+	n, err := fc.Poll(reqCtx)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, facts.StatusFactProcessed, store.status("F1"))
+
+	l, ok := (&facts.Fact{Status: store.status("F2")}).Lease()
+	require.True(t, ok)
+	require.True(t, l.IsRetry())
+	require.Equal(t, 1, l.Attempt)
+
+	// Retry not due yet.
+	n, err = fc.Poll(reqCtx)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+	require.True(t, facts.IsLeaseStatus(store.status("F2")))
+
+	clk.Advance(2 * time.Minute)
+	_, err = fc.Poll(reqCtx)
+	require.NoError(t, err)
+	require.Equal(t, facts.StatusFactFailed, store.status("F2"))
+
+	// A fact leased by another replica is skipped until the lease expires.
+	store.facts["F3"] = &facts.Fact{Id: "F3", NotificationGroup: "ok", Status: facts.FactLease{Owner: "w2", Until: clk.Now().Add(time.Minute), Attempt: 1}.Status()}
+	n, err = fc.Poll(reqCtx)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+}
+
+func TestFactConsumerNoHandler(t *testing.T) {
+
+	store := &memFactsStore{facts: map[string]*facts.Fact{"F1": {Id: "F1", Status: facts.StatusFactActive, NotificationGroup: "other"}}}
+	fc, err := tokensclient.NewFactConsumer(store, tokensclient.FactConsumerConfig{Class: "cls", Group: "grp"})
+	require.NoError(t, err)
+	fc.Handle("ok", func(reqCtx tokensclient.ApiRequestContext, f *facts.Fact) error { return nil })
+
+	n, err := fc.Poll(tokensclient.NewApiRequestContext())
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+	require.Equal(t, facts.StatusFactActive, store.status("F1"))
+}
+
+func TestFactConsumerReplicas(t *testing.T) {
+
+	store := &memFactsStore{facts: make(map[string]*facts.Fact)}
+	for i := 0; i < 200; i++ {
+		id := fmt.Sprintf("F%03d", i)
+		// facts without status are pending as the active ones: the lease expects the empty status.
+		status := facts.StatusFactActive
+		if i%2 == 0 {
+			status = ""
+		}
+		store.facts[id] = &facts.Fact{Id: id, Status: status, NotificationGroup: "ok"}
+	}
+
+	var mu sync.Mutex
+	handled := make(map[string]int)
+	handler := func(reqCtx tokensclient.ApiRequestContext, f *facts.Fact) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled[f.Id]++
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	counts := make([]int, 2)
+	for i := range counts {
+		fc, err := tokensclient.NewFactConsumer(store, tokensclient.FactConsumerConfig{Class: "cls", Group: "grp", Owner: fmt.Sprintf("w%d", i), PageSize: 10})
+		require.NoError(t, err)
+		fc.Handle("ok", handler)
+
+		wg.Add(1)
+		go func(ndx int) {
+			defer wg.Done()
+			counts[ndx], _ = fc.Poll(tokensclient.NewApiRequestContext())
+		}(i)
+	}
+	wg.Wait()
+
+	require.Len(t, handled, 200)
+	for id, n := range handled {
+		require.Equal(t, 1, n, id)
+		require.Equal(t, facts.StatusFactProcessed, store.status(id))
+	}
+	require.Equal(t, 200, counts[0]+counts[1])
+
+	// the empty expected status is sent, not omitted.
+	b, err := json.Marshal(&tokensclient.FactStatusApiRequest{Status: facts.StatusFactProcessed, ExpectedStatus: tokensclient.ExpectStatus("")})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"status": "processed", "expected-status": ""}`, string(b))
+}
@@ -0,0 +1,255 @@
+package bearer
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ActorScope the scope qualifying an actor id. The canonical form is upper case.
+type ActorScope string
+
+func NewActorScope(s string) (ActorScope, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if err := validateIdComponent("actor scope", s, true); err != nil {
+		return "", err
+	}
+
+	return ActorScope(s), nil
+}
+
+func (s ActorScope) String() string {
+	return string(s)
+}
+
+// ActorId an actor id, optionally scoped. The string form is the one used in the apis: <ID>[;scope=<SCOPE>], upper case.
+// The zero value is an empty id. It marshals as a string in json and (through encoding.TextMarshaler) in yaml.
+type ActorId struct {
+	base  string
+	scope ActorScope
+}
+
+// NewActorId builds an actor id from its base and scope. The base may already carry a scope: it is accepted only if it is the same
+// of the scope argument (or the argument is empty), so an id is never scoped twice.
+func NewActorId(id string, scope ActorScope) (ActorId, error) {
+	a, err := ParseActorIdValue(id)
+	if err != nil {
+		return ActorId{}, err
+	}
+
+	return a.WithScope(scope)
+}
+
+func MustNewActorId(id string, scope ActorScope) ActorId {
+	a, err := NewActorId(id, scope)
+	if err != nil {
+		panic(err)
+	}
+
+	return a
+}
+
+// ParseActorIdValue parses the string form of an actor id.
+func ParseActorIdValue(s string) (ActorId, error) {
+	s = strings.TrimSpace(s)
+	if strings.Count(s, ActorScopeMatrixParamValue) > 1 {
+		return ActorId{}, fmt.Errorf("actor id %s scoped more than once", s)
+	}
+
+	base, scope, _ := ParseActorId(s)
+	base = strings.ToUpper(base)
+	if err := validateIdComponent("actor id", base, false); err != nil {
+		return ActorId{}, err
+	}
+
+	sc, err := NewActorScope(scope)
+	if err != nil {
+		return ActorId{}, err
+	}
+
+	if strings.Contains(s, ActorScopeMatrixParamValue) && sc == "" {
+		return ActorId{}, fmt.Errorf("actor id %s has an empty scope", s)
+	}
+
+	return ActorId{base: base, scope: sc}, nil
+}
+
+func (a ActorId) Base() string {
+	return a.base
+}
+
+func (a ActorId) Scope() ActorScope {
+	return a.scope
+}
+
+func (a ActorId) IsZero() bool {
+	return a.base == ""
+}
+
+// WithScope returns the id with the given scope. An empty scope leaves the id as is, a different scope on a scoped id is an error.
+func (a ActorId) WithScope(scope ActorScope) (ActorId, error) {
+	sc, err := NewActorScope(string(scope))
+	if err != nil {
+		return ActorId{}, err
+	}
+
+	if sc == "" || sc == a.scope {
+		return a, nil
+	}
+
+	if a.scope != "" {
+		return ActorId{}, fmt.Errorf("actor id %s already scoped, cannot scope with %s", a, sc)
+	}
+
+	a.scope = sc
+	return a, nil
+}
+
+func (a ActorId) String() string {
+	if a.scope == "" {
+		return a.base
+	}
+
+	return a.base + ActorScopeMatrixParamValue + string(a.scope)
+}
+
+// PathSegment the id escaped for use in a url path. The scope matrix param is kept as is, its value and the base are escaped.
+func (a ActorId) PathSegment() string {
+	if a.scope == "" {
+		return url.PathEscape(a.base)
+	}
+
+	return url.PathEscape(a.base) + ActorScopeMatrixParamValue + url.PathEscape(string(a.scope))
+}
+
+func (a ActorId) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *ActorId) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		*a = ActorId{}
+		return nil
+	}
+
+	v, err := ParseActorIdValue(string(b))
+	if err != nil {
+		return err
+	}
+
+	*a = v
+	return nil
+}
+
+// BearerId the id of the bearer of an actor in a token context: <actor-id>-<context-id>.
+type BearerId struct {
+	actor ActorId
+	ctxId string
+}
+
+func NewBearerId(actor ActorId, ctxId string) (BearerId, error) {
+	if actor.IsZero() {
+		return BearerId{}, errors.New("bearer id requires an actor id")
+	}
+
+	ctxId = strings.ToUpper(strings.TrimSpace(ctxId))
+	if err := validateIdComponent("token context id", ctxId, false); err != nil {
+		return BearerId{}, err
+	}
+
+	return BearerId{actor: actor, ctxId: ctxId}, nil
+}
+
+func MustNewBearerId(actor ActorId, ctxId string) BearerId {
+	b, err := NewBearerId(actor, ctxId)
+	if err != nil {
+		panic(err)
+	}
+
+	return b
+}
+
+// ParseBearerIdValue parses the string form of a bearer id.
+func ParseBearerIdValue(s string) (BearerId, error) {
+	ndx := strings.LastIndex(s, "-")
+	if ndx < 0 {
+		return BearerId{}, fmt.Errorf("invalid bearer id %s", s)
+	}
+
+	a, err := ParseActorIdValue(s[:ndx])
+	if err != nil {
+		return BearerId{}, err
+	}
+
+	return NewBearerId(a, s[ndx+1:])
+}
+
+func (b BearerId) Actor() ActorId {
+	return b.actor
+}
+
+func (b BearerId) ContextId() string {
+	return b.ctxId
+}
+
+func (b BearerId) IsZero() bool {
+	return b.actor.IsZero()
+}
+
+func (b BearerId) String() string {
+	if b.IsZero() {
+		return ""
+	}
+
+	return b.actor.String() + "-" + b.ctxId
+}
+
+func (b BearerId) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+func (b *BearerId) UnmarshalText(t []byte) error {
+	if len(t) == 0 {
+		*b = BearerId{}
+		return nil
+	}
+
+	v, err := ParseBearerIdValue(string(t))
+	if err != nil {
+		return err
+	}
+
+	*b = v
+	return nil
+}
+
+// ActorRef the typed actor id of the bearer.
+func (ber *Bearer) ActorRef() (ActorId, error) {
+	return NewActorId(ber.ActorId, ActorScope(ber.ActorScope))
+}
+
+// BearerId the typed id of the bearer.
+func (ber *Bearer) BearerId() (BearerId, error) {
+	a, err := ber.ActorRef()
+	if err != nil {
+		return BearerId{}, err
+	}
+
+	return NewBearerId(a, ber.TokenContextId)
+}
+
+func validateIdComponent(what, s string, allowEmpty bool) error {
+	if s == "" {
+		if allowEmpty {
+			return nil
+		}
+		return fmt.Errorf("%s is empty", what)
+	}
+
+	if strings.ContainsAny(s, ";=-/?# \t\r\n") {
+		return fmt.Errorf("%s %s contains invalid characters", what, s)
+	}
+
+	return nil
+}
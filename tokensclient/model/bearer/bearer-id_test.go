@@ -0,0 +1,86 @@
+package bearer_test
+
+import (
+	"encoding/json"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/bearer"
+	"github.com/mitchellh/mapstructure"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+	"testing"
+)
+
+func TestActorId(t *testing.T) {
+
+	a, err := bearer.NewActorId("mprmls62s21g337j", "bpmgm")
+	require.NoError(t, err)
+	require.Equal(t, "MPRMLS62S21G337J;scope=BPMGM", a.String())
+
+	// same scope twice is tolerated, a different one is not.
+	_, err = bearer.NewActorId(a.String(), "BPMGM")
+	require.NoError(t, err)
+	_, err = bearer.NewActorId(a.String(), "other")
+	require.Error(t, err)
+
+	_, err = bearer.ParseActorIdValue("MPRMLS62S21G337J;scope=A;scope=B")
+	require.Error(t, err)
+
+	_, err = bearer.ParseActorIdValue("")
+	require.Error(t, err)
+
+	b, err := bearer.NewBearerId(a, "bpmgm1")
+	require.NoError(t, err)
+	require.Equal(t, "MPRMLS62S21G337J;scope=BPMGM-BPMGM1", b.String())
+
+	pb, err := bearer.ParseBearerIdValue(b.String())
+	require.NoError(t, err)
+	require.Equal(t, b, pb)
+
+	type holder struct {
+		Actor  bearer.ActorId  `json:"actor"`
+		Bearer bearer.BearerId `json:"bearer"`
+	}
+
+	data, err := json.Marshal(holder{Actor: a, Bearer: b})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"actor": "MPRMLS62S21G337J;scope=BPMGM", "bearer": "MPRMLS62S21G337J;scope=BPMGM-BPMGM1"}`, string(data))
+
+	var h holder
+	require.NoError(t, json.Unmarshal(data, &h))
+	require.Equal(t, a, h.Actor)
+	require.Error(t, json.Unmarshal([]byte(`{"actor": "A;scope=X;scope=Y"}`), &h))
+
+	ber := bearer.NewBearer("mprmls62s21g337j", "bpmgm", "bpmgm1")
+	bid, err := ber.BearerId()
+	require.NoError(t, err)
+	require.Equal(t, b, bid)
+}
+
+func TestActorIdConfig(t *testing.T) {
+
+	type holder struct {
+		Actor  bearer.ActorId  `yaml:"actor,omitempty" mapstructure:"actor,omitempty" json:"actor,omitempty"`
+		Bearer bearer.BearerId `yaml:"bearer,omitempty" mapstructure:"bearer,omitempty" json:"bearer,omitempty"`
+	}
+
+	a := bearer.MustNewActorId("mprmls62s21g337j", "bpmgm")
+	h := holder{Actor: a, Bearer: bearer.MustNewBearerId(a, "bpmgm1")}
+
+	data, err := yaml.Marshal(&h)
+	require.NoError(t, err)
+	require.YAMLEq(t, "actor: MPRMLS62S21G337J;scope=BPMGM\nbearer: MPRMLS62S21G337J;scope=BPMGM-BPMGM1\n", string(data))
+
+	var yh holder
+	require.NoError(t, yaml.Unmarshal(data, &yh))
+	require.Equal(t, h, yh)
+	require.Error(t, yaml.Unmarshal([]byte("actor: A;scope=X;scope=Y\n"), &yh))
+
+	// as read by viper: plain strings decoded through the text unmarshaler hook.
+	var m map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(data, &m))
+
+	var mh holder
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{DecodeHook: mapstructure.TextUnmarshallerHookFunc(), Result: &mh})
+	require.NoError(t, err)
+	require.NoError(t, dec.Decode(m))
+	require.Equal(t, h, mh)
+}
@@ -0,0 +1,386 @@
+package facts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"sync"
+	"unicode/utf8"
+)
+
+// FactSchema the subset of JSON schema supported for fact properties: type, properties, required, additionalProperties, items, enum,
+// minLength, maxLength, pattern, minimum and maximum.
+type FactSchema struct {
+	Type                 string                 `yaml:"type,omitempty" mapstructure:"type,omitempty" json:"type,omitempty"`
+	Properties           map[string]*FactSchema `yaml:"properties,omitempty" mapstructure:"properties,omitempty" json:"properties,omitempty"`
+	Required             []string               `yaml:"required,omitempty" mapstructure:"required,omitempty" json:"required,omitempty"`
+	AdditionalProperties *bool                  `yaml:"additionalProperties,omitempty" mapstructure:"additionalProperties,omitempty" json:"additionalProperties,omitempty"`
+	Items                *FactSchema            `yaml:"items,omitempty" mapstructure:"items,omitempty" json:"items,omitempty"`
+	Enum                 []interface{}          `yaml:"enum,omitempty" mapstructure:"enum,omitempty" json:"enum,omitempty"`
+	MinLength            *int                   `yaml:"minLength,omitempty" mapstructure:"minLength,omitempty" json:"minLength,omitempty"`
+	MaxLength            *int                   `yaml:"maxLength,omitempty" mapstructure:"maxLength,omitempty" json:"maxLength,omitempty"`
+	Pattern              string                 `yaml:"pattern,omitempty" mapstructure:"pattern,omitempty" json:"pattern,omitempty"`
+	Minimum              *float64               `yaml:"minimum,omitempty" mapstructure:"minimum,omitempty" json:"minimum,omitempty"`
+	Maximum              *float64               `yaml:"maximum,omitempty" mapstructure:"maximum,omitempty" json:"maximum,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// DeserializeFactSchema parses the schema and compiles its patterns: an invalid pattern is an error.
+func DeserializeFactSchema(b []byte) (*FactSchema, error) {
+	s := FactSchema{}
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.Compile(); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// Compile compiles the patterns of the schema and of the nested ones. Schemas not compiled compile their patterns on each validation.
+func (s *FactSchema) Compile() error {
+	return s.compile("$")
+}
+
+func (s *FactSchema) compile(path string) error {
+	if s == nil {
+		return nil
+	}
+
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pattern %s: %w", path, s.Pattern, err)
+		}
+		s.pattern = re
+	}
+
+	for n, ps := range s.Properties {
+		if err := ps.compile(path + "." + n); err != nil {
+			return err
+		}
+	}
+
+	return s.Items.compile(path + "[]")
+}
+
+// Validate checks a value as decoded by encoding/json against the schema.
+func (s *FactSchema) Validate(v interface{}) error {
+	return s.validate("$", v)
+}
+
+func (s *FactSchema) validate(path string, v interface{}) error {
+	if s == nil {
+		return nil
+	}
+
+	switch s.Type {
+	case "":
+	case "object":
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected object, got %T", path, v)
+		}
+		return s.validateObject(path, m)
+	case "array":
+		a, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected array, got %T", path, v)
+		}
+		for i, item := range a {
+			if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string, got %T", path, v)
+		}
+		if err := s.validateString(path, str); err != nil {
+			return err
+		}
+	case "number", "integer":
+		f, ok := v.(float64)
+		if !ok {
+			return fmt.Errorf("%s: expected %s, got %T", path, s.Type, v)
+		}
+		if s.Type == "integer" && f != float64(int64(f)) {
+			return fmt.Errorf("%s: expected integer, got %v", path, f)
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			return fmt.Errorf("%s: %v is less than minimum %v", path, f, *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			return fmt.Errorf("%s: %v is greater than maximum %v", path, f, *s.Maximum)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean, got %T", path, v)
+		}
+	default:
+		return fmt.Errorf("%s: unsupported schema type %s", path, s.Type)
+	}
+
+	if len(s.Enum) > 0 {
+		for _, e := range s.Enum {
+			if reflect.DeepEqual(e, v) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: value %v not in enum", path, v)
+	}
+
+	return nil
+}
+
+func (s *FactSchema) validateObject(path string, m map[string]interface{}) error {
+	for _, r := range s.Required {
+		if _, ok := m[r]; !ok {
+			return fmt.Errorf("%s: missing required property %s", path, r)
+		}
+	}
+
+	// sorted to report always the same error.
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		ps, ok := s.Properties[k]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				return fmt.Errorf("%s: property %s not allowed", path, k)
+			}
+			continue
+		}
+
+		if err := ps.validate(path+"."+k, m[k]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *FactSchema) validateString(path string, str string) error {
+	// lengths count characters, not bytes.
+	n := utf8.RuneCountInString(str)
+	if s.MinLength != nil && n < *s.MinLength {
+		return fmt.Errorf("%s: length %d is less than minLength %d", path, n, *s.MinLength)
+	}
+
+	if s.MaxLength != nil && n > *s.MaxLength {
+		return fmt.Errorf("%s: length %d is greater than maxLength %d", path, n, *s.MaxLength)
+	}
+
+	if s.Pattern != "" {
+		re := s.pattern
+		if re == nil {
+			var err error
+			if re, err = regexp.Compile(s.Pattern); err != nil {
+				return fmt.Errorf("%s: invalid pattern %s: %w", path, s.Pattern, err)
+			}
+		}
+		if !re.MatchString(str) {
+			return fmt.Errorf("%s: %s does not match pattern %s", path, str, s.Pattern)
+		}
+	}
+
+	return nil
+}
+
+// FactClass the declaration of a fact class: the Go struct type of its properties and an optional schema.
+type FactClass struct {
+	Name   string
+	Type   reflect.Type
+	Schema *FactSchema
+}
+
+var factClassesRegistry = struct {
+	mu      sync.RWMutex
+	classes map[string]*FactClass
+}{classes: make(map[string]*FactClass)}
+
+// RegisterFactClass declares the class with the struct type of the prototype (a struct or a pointer to struct) and a json schema.
+// schema can be nil. Registering a class twice is an error.
+func RegisterFactClass(name string, prototype interface{}, schema []byte) (*FactClass, error) {
+	if name == "" {
+		return nil, errors.New("fact class name missing")
+	}
+
+	t := reflect.TypeOf(prototype)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("fact class %s: prototype must be a struct, got %T", name, prototype)
+	}
+
+	fc := FactClass{Name: name, Type: t}
+	if len(schema) > 0 {
+		s, err := DeserializeFactSchema(schema)
+		if err != nil {
+			return nil, fmt.Errorf("fact class %s: invalid schema: %w", name, err)
+		}
+		fc.Schema = s
+	}
+
+	factClassesRegistry.mu.Lock()
+	defer factClassesRegistry.mu.Unlock()
+	if _, ok := factClassesRegistry.classes[name]; ok {
+		return nil, fmt.Errorf("fact class %s already registered", name)
+	}
+
+	factClassesRegistry.classes[name] = &fc
+	return &fc, nil
+}
+
+// UnregisterFactClass removes the class from the registry. Meant for tests and for applications reloading their declarations.
+func UnregisterFactClass(name string) {
+	factClassesRegistry.mu.Lock()
+	defer factClassesRegistry.mu.Unlock()
+	delete(factClassesRegistry.classes, name)
+}
+
+func MustRegisterFactClass(name string, prototype interface{}, schema []byte) *FactClass {
+	fc, err := RegisterFactClass(name, prototype, schema)
+	if err != nil {
+		panic(err)
+	}
+
+	return fc
+}
+
+func GetFactClass(name string) (*FactClass, bool) {
+	factClassesRegistry.mu.RLock()
+	defer factClassesRegistry.mu.RUnlock()
+	fc, ok := factClassesRegistry.classes[name]
+	return fc, ok
+}
+
+// EncodeProperties converts a value of the class type into the properties of a fact and validates them.
+func (fc *FactClass) EncodeProperties(v interface{}) (map[string]interface{}, error) {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t != fc.Type {
+		return nil, fmt.Errorf("fact class %s: expected %s, got %T", fc.Name, fc.Type, v)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	err = fc.ValidateProperties(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// ValidateProperties checks the properties against the schema and, strictly, against the fields of the class type: unknown fields are rejected.
+// The properties are normalized through json first so that Go values (i.e. int) are checked as they are sent.
+func (fc *FactClass) ValidateProperties(m map[string]interface{}) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	if fc.Schema != nil {
+		var v interface{}
+		if err = json.Unmarshal(b, &v); err != nil {
+			return err
+		}
+
+		if err = fc.Schema.Validate(v); err != nil {
+			return fmt.Errorf("fact class %s: %w", fc.Name, err)
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(reflect.New(fc.Type).Interface())
+	if err != nil {
+		return fmt.Errorf("fact class %s: %w", fc.Name, err)
+	}
+
+	return nil
+}
+
+// DecodeProperties returns a pointer to a new value of the class type filled with the properties.
+func (fc *FactClass) DecodeProperties(m map[string]interface{}) (interface{}, error) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	v := reflect.New(fc.Type).Interface()
+	err = json.Unmarshal(b, v)
+	if err != nil {
+		return nil, fmt.Errorf("fact class %s: %w", fc.Name, err)
+	}
+
+	return v, nil
+}
+
+// TypedFact a fact with its properties decoded into the type registered for its class.
+type TypedFact struct {
+	Fact
+	Value interface{} `yaml:"-" mapstructure:"-" json:"-"`
+}
+
+// DecodeFact validates the properties of the fact and decodes them into the class type.
+func (fc *FactClass) DecodeFact(f *Fact) (TypedFact, error) {
+	if err := fc.ValidateProperties(f.Properties); err != nil {
+		return TypedFact{}, fmt.Errorf("fact %s: %w", f.Id, err)
+	}
+
+	v, err := fc.DecodeProperties(f.Properties)
+	if err != nil {
+		return TypedFact{}, fmt.Errorf("fact %s: %w", f.Id, err)
+	}
+
+	return TypedFact{Fact: *f, Value: v}, nil
+}
+
+// TypedProperties decodes the properties of the fact into the type registered for its class.
+func (ctx *Fact) TypedProperties() (interface{}, error) {
+	fc, ok := GetFactClass(ctx.Class)
+	if !ok {
+		return nil, fmt.Errorf("fact class %s not registered", ctx.Class)
+	}
+
+	return fc.DecodeProperties(ctx.Properties)
+}
+
+// DecodeProperties decodes the properties of the fact into target, a pointer to a struct.
+func (ctx *Fact) DecodeProperties(target interface{}) error {
+	b, err := json.Marshal(ctx.Properties)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, target)
+}
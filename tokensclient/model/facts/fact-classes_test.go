@@ -0,0 +1,70 @@
+package facts_test
+
+import (
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/facts"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type acquisto struct {
+	CodiceFiscale string  `json:"codice-fiscale"`
+	Importo       float64 `json:"importo"`
+	Canale        string  `json:"canale,omitempty"`
+	Note          string  `json:"note,omitempty"`
+}
+
+var acquistoSchema = []byte(`{
+  "type": "object",
+  "required": ["codice-fiscale", "importo"],
+  "properties": {
+    "codice-fiscale": { "type": "string", "minLength": 16, "maxLength": 16 },
+    "importo": { "type": "number", "minimum": 0 },
+    "canale": { "type": "string", "enum": ["web", "app"] },
+    "note": { "type": "string", "maxLength": 4, "pattern": "^[a-zà-ù]+$" }
+  }
+}`)
+
+func TestFactClasses(t *testing.T) {
+
+	fc, err := facts.RegisterFactClass("acquisto", &acquisto{}, acquistoSchema)
+	require.NoError(t, err)
+	defer facts.UnregisterFactClass("acquisto")
+
+	_, err = facts.RegisterFactClass("acquisto", acquisto{}, nil)
+	require.Error(t, err)
+
+	m, err := fc.EncodeProperties(&acquisto{CodiceFiscale: "MPRMLS62S21G337J", Importo: 10.5, Canale: "app"})
+	require.NoError(t, err)
+	require.Equal(t, 10.5, m["importo"])
+
+	_, err = fc.EncodeProperties(&acquisto{CodiceFiscale: "MPRMLS62S21G337J", Importo: -1})
+	require.Error(t, err)
+
+	_, err = fc.EncodeProperties(struct{ Importo float64 }{Importo: 1})
+	require.Error(t, err)
+
+	require.NoError(t, fc.ValidateProperties(map[string]interface{}{"codice-fiscale": "MPRMLS62S21G337J", "importo": 1}))
+	require.Error(t, fc.ValidateProperties(map[string]interface{}{"codice-fiscale": "MPRMLS62S21G337J", "importo": 1.0, "canale": "sms"}))
+	require.Error(t, fc.ValidateProperties(map[string]interface{}{"codiceFiscale": "MPRMLS62S21G337J", "codice-fiscale": "MPRMLS62S21G337J", "importo": 1.0}))
+
+	// lengths in characters: "però" is 4 characters and 5 bytes.
+	require.NoError(t, fc.ValidateProperties(map[string]interface{}{"codice-fiscale": "MPRMLS62S21G337J", "importo": 1, "note": "però"}))
+	require.Error(t, fc.ValidateProperties(map[string]interface{}{"codice-fiscale": "MPRMLS62S21G337J", "importo": 1, "note": "perché"}))
+	require.Error(t, fc.ValidateProperties(map[string]interface{}{"codice-fiscale": "MPRMLS62S21G337J", "importo": 1, "note": "AB"}))
+
+	// malformed patterns are rejected on registration.
+	_, err = facts.RegisterFactClass("acquisto-bad-pattern", &acquisto{}, []byte(`{"type": "object", "properties": {"note": {"type": "string", "pattern": "[a-z"}}}`))
+	require.Error(t, err)
+
+	f := facts.Fact{Class: "acquisto", Properties: m}
+	v, err := f.TypedProperties()
+	require.NoError(t, err)
+	require.Equal(t, "app", v.(*acquisto).Canale)
+
+	tf, err := fc.DecodeFact(&f)
+	require.NoError(t, err)
+	require.Equal(t, 10.5, tf.Value.(*acquisto).Importo)
+
+	_, err = fc.DecodeFact(&facts.Fact{Id: "F2", Class: "acquisto", Properties: map[string]interface{}{"importo": "10"}})
+	require.Error(t, err)
+}
@@ -0,0 +1,127 @@
+package facts
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	StatusFactLeasePrefix = "lease;"
+	StatusFactRetryPrefix = "retry;"
+
+	factLeaseOwnerKey   = "owner"
+	factLeaseUntilKey   = "until"
+	factLeaseAttemptKey = "attempt"
+)
+
+// FactLease ownership marker stored in the status of a fact while a consumer is handling it. A lease without owner is the marker
+// of a failed attempt scheduled for retry: Until is the instant from which the fact can be picked up again.
+type FactLease struct {
+	Owner   string
+	Until   time.Time
+	Attempt int
+}
+
+func (l FactLease) IsRetry() bool {
+	return l.Owner == ""
+}
+
+func (l FactLease) IsExpiredAt(asOf time.Time) bool {
+	return !asOf.Before(l.Until)
+}
+
+// Status encodes the lease as a fact status: lease;owner=<owner>;until=<unix-millis>;attempt=<n>
+func (l FactLease) Status() string {
+	var sb strings.Builder
+	if l.IsRetry() {
+		sb.WriteString(StatusFactRetryPrefix)
+	} else {
+		sb.WriteString(StatusFactLeasePrefix)
+		sb.WriteString(factLeaseOwnerKey + "=" + l.Owner + ";")
+	}
+
+	sb.WriteString(factLeaseUntilKey + "=" + strconv.FormatInt(l.Until.UnixMilli(), 10) + ";")
+	sb.WriteString(factLeaseAttemptKey + "=" + strconv.Itoa(l.Attempt))
+	return sb.String()
+}
+
+func IsLeaseStatus(s string) bool {
+	return strings.HasPrefix(s, StatusFactLeasePrefix) || strings.HasPrefix(s, StatusFactRetryPrefix)
+}
+
+func ParseFactLease(s string) (FactLease, error) {
+	var l FactLease
+	var rest string
+	switch {
+	case strings.HasPrefix(s, StatusFactLeasePrefix):
+		rest = strings.TrimPrefix(s, StatusFactLeasePrefix)
+	case strings.HasPrefix(s, StatusFactRetryPrefix):
+		rest = strings.TrimPrefix(s, StatusFactRetryPrefix)
+	default:
+		return l, fmt.Errorf("status %s is not a lease", s)
+	}
+
+	for _, kv := range strings.Split(rest, ";") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return l, fmt.Errorf("invalid lease status %s", s)
+		}
+
+		switch k {
+		case factLeaseOwnerKey:
+			l.Owner = v
+		case factLeaseUntilKey:
+			ms, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return l, err
+			}
+			l.Until = time.UnixMilli(ms)
+		case factLeaseAttemptKey:
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return l, err
+			}
+			l.Attempt = n
+		}
+	}
+
+	if strings.HasPrefix(s, StatusFactLeasePrefix) && l.Owner == "" {
+		return l, errors.New("lease status without owner")
+	}
+
+	return l, nil
+}
+
+// Lease returns the lease marker of the fact if any.
+func (ctx *Fact) Lease() (FactLease, bool) {
+	if !IsLeaseStatus(ctx.Status) {
+		return FactLease{}, false
+	}
+
+	l, err := ParseFactLease(ctx.Status)
+	if err != nil {
+		return FactLease{}, false
+	}
+
+	return l, true
+}
+
+// IsPendingAt the fact has still to be handled as of the given instant: it is active (or has no status), or it carries an expired lease.
+func (ctx *Fact) IsPendingAt(asOf time.Time) bool {
+	switch ctx.Status {
+	case "", StatusFactActive:
+		return true
+	case StatusFactProcessed, StatusFactFailed:
+		return false
+	}
+
+	l, ok := ctx.Lease()
+	if !ok {
+		return false
+	}
+
+	return l.IsExpiredAt(asOf)
+}
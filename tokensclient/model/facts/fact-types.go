@@ -5,6 +5,12 @@ import (
 	"strings"
 )
 
+const (
+	StatusFactActive    = "active"
+	StatusFactProcessed = "processed"
+	StatusFactFailed    = "failed"
+)
+
 type Fact struct {
 	Class             string                 `yaml:"class,omitempty" mapstructure:"class,omitempty" json:"class,omitempty"`
 	Group             string                 `yaml:"group,omitempty" mapstructure:"group,omitempty" json:"group,omitempty"`
@@ -15,6 +21,7 @@ type Fact struct {
 	Status            string                 `yaml:"status,omitempty" mapstructure:"status,omitempty" json:"status,omitempty"`
 	Properties        map[string]interface{} `yaml:"properties,omitempty" mapstructure:"properties,omitempty" json:"properties,omitempty"`
 	NotificationGroup string                 `yaml:"notification-group,omitempty" mapstructure:"notification-group,omitempty" json:"notification-group,omitempty"`
+	Ts                string                 `yaml:"ts,omitempty" mapstructure:"ts,omitempty" json:"ts,omitempty"`
 	TTL               int                    `yaml:"ttl,omitempty" mapstructure:"ttl,omitempty" json:"ttl,omitempty"`
 }
 
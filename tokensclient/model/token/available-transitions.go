@@ -0,0 +1,134 @@
+package token
+
+import (
+	"encoding/json"
+	"github.com/rs/zerolog/log"
+	"sort"
+)
+
+type AvailableTransition struct {
+	Name           string               `yaml:"name,omitempty" mapstructure:"name,omitempty" json:"name,omitempty"`
+	To             string               `yaml:"to,omitempty" mapstructure:"to,omitempty" json:"to,omitempty"`
+	Order          int                  `yaml:"order,omitempty" mapstructure:"order,omitempty" json:"order,omitempty"`
+	Description    string               `yaml:"description,omitempty" mapstructure:"description,omitempty" json:"description,omitempty"`
+	Catch          bool                 `yaml:"catch,omitempty" mapstructure:"catch,omitempty" json:"catch,omitempty"`
+	Properties     []Property           `yaml:"properties,omitempty" mapstructure:"properties,omitempty" json:"properties,omitempty"`
+	Help           CodeDescriptionPair  `yaml:"help,omitempty" mapstructure:"help,omitempty" json:"help,omitempty"`
+	RulesSatisfied bool                 `yaml:"rules-satisfied,omitempty" mapstructure:"rules-satisfied,omitempty" json:"rules-satisfied,omitempty"`
+	FailedRule     *Rule                `yaml:"failed-rule,omitempty" mapstructure:"failed-rule,omitempty" json:"failed-rule,omitempty"`
+	Diagnostics    []PropertyDiagnostic `yaml:"diagnostics,omitempty" mapstructure:"diagnostics,omitempty" json:"diagnostics,omitempty"`
+}
+
+// IsEnabled the transition can be taken with the supplied input: rules are satisfied and properties are valid.
+func (at *AvailableTransition) IsEnabled() bool {
+	return at.RulesSatisfied && len(at.Diagnostics) == 0
+}
+
+type AvailableTransitions struct {
+	CtxId       string                `yaml:"ctx-id,omitempty" mapstructure:"ctx-id,omitempty" json:"ctx-id,omitempty"`
+	TokenId     string                `yaml:"token-id,omitempty" mapstructure:"token-id,omitempty" json:"token-id,omitempty"`
+	State       string                `yaml:"state,omitempty" mapstructure:"state,omitempty" json:"state,omitempty"`
+	Transitions []AvailableTransition `yaml:"transitions,omitempty" mapstructure:"transitions,omitempty" json:"transitions,omitempty"`
+}
+
+func (ats *AvailableTransitions) FindByName(n string) (AvailableTransition, bool) {
+	for _, at := range ats.Transitions {
+		if at.Name == n {
+			return at, true
+		}
+	}
+
+	return AvailableTransition{}, false
+}
+
+func (ats *AvailableTransitions) Enabled() []AvailableTransition {
+	var res []AvailableTransition
+	for _, at := range ats.Transitions {
+		if at.IsEnabled() {
+			res = append(res, at)
+		}
+	}
+
+	return res
+}
+
+func DeserializeAvailableTransitions(b []byte) (*AvailableTransitions, error) {
+	ats := AvailableTransitions{}
+	err := json.Unmarshal(b, &ats)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ats, nil
+}
+
+// AvailableTransitions lists the out and catch transitions of the state of the token ordered by their Order, evaluating rules and properties
+// against the supplied input. tok is nil for tokens still to be created.
+func (ctx *TokenContext) AvailableTransitions(tok *Token, input map[string]interface{}) (*AvailableTransitions, error) {
+
+	const semLogContext = "token-context::available-transitions"
+
+	ats := AvailableTransitions{CtxId: ctx.Id, State: StartEndState}
+	var vars ProcessVars
+	if tok != nil {
+		ats.TokenId = tok.Id
+		if len(tok.Events) > 0 {
+			ats.State = tok.FindCurrentState()
+			vars = tok.Vars()
+		}
+	}
+
+	sd, err := ctx.StateMachine.FindStateDefinition(ats.State)
+	if err != nil {
+		return nil, err
+	}
+
+	eCtx, err := newTransitionExpressionContext(vars, input)
+	if err != nil {
+		return nil, err
+	}
+
+	add := func(tr Transition, catch bool) {
+		at := AvailableTransition{
+			Name:        tr.Name,
+			To:          tr.To,
+			Order:       tr.Order,
+			Description: tr.Description,
+			Catch:       catch,
+			Properties:  tr.Properties,
+		}
+
+		if to, err := ctx.StateMachine.FindStateDefinition(tr.To); err == nil {
+			at.Help = to.Help
+		}
+
+		ok, ndx, err := tr.CheckRules(eCtx)
+		if err != nil {
+			log.Warn().Err(err).Str("transition", tr.Name).Msg(semLogContext + " rule evaluation error")
+		}
+
+		at.RulesSatisfied = ok
+		if !ok && ndx >= 0 {
+			r := tr.Rules[ndx]
+			r.Help = evalHelp(eCtx, r.Help)
+			at.FailedRule = &r
+		}
+
+		at.Diagnostics = tr.ValidateProperties(EventTypeNext.Scope(), eCtx, input)
+		ats.Transitions = append(ats.Transitions, at)
+	}
+
+	for _, tr := range sd.OutTransitions {
+		add(tr, false)
+	}
+
+	for _, tr := range ctx.StateMachine.CatchTransitions {
+		add(tr, true)
+	}
+
+	sort.SliceStable(ats.Transitions, func(i, j int) bool {
+		return ats.Transitions[i].Order < ats.Transitions[j].Order
+	})
+
+	return &ats, nil
+}
@@ -0,0 +1,26 @@
+package token_test
+
+import (
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAvailableTransitions(t *testing.T) {
+
+	tok := token.Token{Id: "TOK1", Events: []token.Event{{State: token.State{Code: "generato"}}}}
+	ats, err := validationTestContext.AvailableTransitions(&tok, map[string]interface{}{"result": "KO"})
+	require.NoError(t, err)
+	require.Equal(t, "generato", ats.State)
+	require.Len(t, ats.Transitions, 2)
+
+	at, ok := ats.FindByName("uso-ok")
+	require.True(t, ok)
+	require.False(t, at.RulesSatisfied)
+	require.NotNil(t, at.FailedRule)
+	require.Len(t, at.Diagnostics, 0)
+
+	enabled := ats.Enabled()
+	require.Len(t, enabled, 1)
+	require.Equal(t, "uso-ko", enabled[0].Name)
+}
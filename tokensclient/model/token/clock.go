@@ -0,0 +1,117 @@
+package token
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock source of the current instant of the components evaluating time dependent logic (timer scheduler, fact consumer, campaign
+// filters and portfolio). The model itself takes the instant explicitly: each time dependent method has an *At variant, the plain
+// one uses the system time.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+var SystemClock Clock = systemClock{}
+
+// ClockTimer a timer armed on a clock, see AfterFunc.
+type ClockTimer interface {
+	Stop() bool
+}
+
+// AfterFunc runs f in its own goroutine once the clock has moved d past the current instant. Clocks providing their own AfterFunc,
+// as FakeClock does, are honoured; the others run on the system time.
+func AfterFunc(c Clock, d time.Duration, f func()) ClockTimer {
+	if tc, ok := c.(interface {
+		AfterFunc(d time.Duration, f func()) ClockTimer
+	}); ok {
+		return tc.AfterFunc(d, f)
+	}
+
+	return time.AfterFunc(d, f)
+}
+
+// FakeClock a clock that stays still unless explicitly moved. Meant for tests. The timers armed on it fire when the clock is moved
+// past their deadline.
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	fc       *FakeClock
+	deadline time.Time
+	f        func()
+}
+
+// Stop disarms the timer. It returns false if the timer already fired or has been stopped.
+func (ft *fakeTimer) Stop() bool {
+	ft.fc.mu.Lock()
+	defer ft.fc.mu.Unlock()
+	for i, t := range ft.fc.timers {
+		if t == ft {
+			ft.fc.timers = append(ft.fc.timers[:i], ft.fc.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (fc *FakeClock) Now() time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.now
+}
+
+func (fc *FakeClock) Set(now time.Time) {
+	fc.mu.Lock()
+	fc.now = now
+	fc.fire()
+}
+
+func (fc *FakeClock) Advance(d time.Duration) time.Time {
+	fc.mu.Lock()
+	fc.now = fc.now.Add(d)
+	now := fc.now
+	fc.fire()
+	return now
+}
+
+func (fc *FakeClock) AfterFunc(d time.Duration, f func()) ClockTimer {
+	fc.mu.Lock()
+	ft := &fakeTimer{fc: fc, deadline: fc.now.Add(d), f: f}
+	fc.timers = append(fc.timers, ft)
+	fc.fire()
+	return ft
+}
+
+// fire runs the timers due and releases the lock held by the caller.
+func (fc *FakeClock) fire() {
+	var due []*fakeTimer
+	timers := fc.timers[:0]
+	for _, t := range fc.timers {
+		if t.deadline.After(fc.now) {
+			timers = append(timers, t)
+		} else {
+			due = append(due, t)
+		}
+	}
+	fc.timers = timers
+	fc.mu.Unlock()
+
+	for _, t := range due {
+		go t.f()
+	}
+}
@@ -24,86 +24,6 @@ const (
 	SysParamNameTokenContextId = "_ctxId"
 )
 
-type Timeline struct {
-	StartDate      string `yaml:"start-date,omitempty" mapstructure:"start-date,omitempty" json:"start-date,omitempty"`
-	EndDate        string `yaml:"end-date,omitempty" mapstructure:"end-date,omitempty" json:"end-date,omitempty"`
-	ExpirationMode string `yaml:"expiration-mode,omitempty" mapstructure:"expiration-mode,omitempty" json:"expiration-mode,omitempty"`
-}
-
-func (tl *Timeline) IsOver() bool {
-	today := time.Now().Format("20060102")
-	if today > tl.EndDate {
-		return true
-	}
-
-	return false
-}
-
-func (tl *Timeline) IsNotStartedYet() bool {
-	today := time.Now().Format("20060102")
-	if today < tl.StartDate {
-		return true
-	}
-
-	return false
-}
-
-func (tl *Timeline) IsInRange() bool {
-	today := time.Now().Format("20060102")
-	if today >= tl.StartDate && today <= tl.EndDate {
-		return true
-	}
-
-	return false
-}
-
-func (tl *Timeline) NumberOfDays() (int, error) {
-	end, err := time.Parse("20060102", tl.EndDate)
-	if err != nil {
-		return -1, err
-	}
-
-	start, err := time.Parse("20060102", tl.StartDate)
-	if err != nil {
-		return -1, err
-	}
-
-	numDays := end.Sub(start).Hours() / 24.0
-	return int(numDays), nil
-}
-
-func (tl *Timeline) Valid() bool {
-
-	const semLogContext = "context timeline validation"
-
-	if tl.StartDate == "" {
-		tl.StartDate = time.Now().Format("20060102")
-	} else {
-		_, err := time.Parse("20060102", tl.StartDate)
-		if err != nil {
-			log.Error().Err(err).Str("start-date", tl.StartDate).Str("end-date", tl.EndDate).Msg(semLogContext + " invalid start-date format")
-			return false
-		}
-	}
-
-	if tl.EndDate == "" {
-		tl.EndDate = time.Now().Format("20060102")
-	} else {
-		_, err := time.Parse("20060102", tl.EndDate)
-		if err != nil {
-			log.Error().Err(err).Str("start-date", tl.StartDate).Str("end-date", tl.EndDate).Msg(semLogContext + " invalid end-date format")
-			return false
-		}
-	}
-
-	if tl.StartDate > tl.EndDate {
-		log.Error().Str("start-date", tl.StartDate).Str("end-date", tl.EndDate).Msg(semLogContext + " invalid range interval")
-		return false
-	}
-
-	return true
-}
-
 type TokenIdProviderType struct {
 	ProviderType string `yaml:"type,omitempty" mapstructure:"type,omitempty" json:"type,omitempty"`
 	Unique       bool   `yaml:"unique,omitempty" mapstructure:"unique,omitempty" json:"unique,omitempty"`
@@ -169,7 +89,28 @@ func (ctx *TokenContext) Valid() bool {
 }
 
 func (ctx *TokenContext) IsActive() bool {
-	return ctx.Timeline.IsInRange()
+	return ctx.IsActiveAt(time.Now())
+}
+
+func (ctx *TokenContext) IsActiveAt(asOf time.Time) bool {
+	return ctx.Timeline.IsInRangeAt(asOf)
+}
+
+// IsTokenExpired evaluates the expiry of the token with the expiration mode and the location of the timeline of the context.
+func (ctx *TokenContext) IsTokenExpired(tok *Token) bool {
+	return ctx.IsTokenExpiredAt(tok, time.Now())
+}
+
+// IsTokenExpiredAt an invalid location of the timeline makes the token expired, as an invalid expiry does.
+func (ctx *TokenContext) IsTokenExpiredAt(tok *Token, asOf time.Time) bool {
+	const semLogContext = "token-context::is-token-expired"
+	loc, err := ctx.Timeline.Loc()
+	if err != nil {
+		log.Error().Err(err).Str("ctx-id", ctx.Id).Str("location", ctx.Timeline.Location).Msg(semLogContext + " invalid timeline location")
+		return true
+	}
+
+	return tok.IsExpiredAt(ctx.Timeline.ExpirationMode, loc, asOf)
 }
 
 func (ctx *TokenContext) EvaluateInActions(scope string, tok *Token, params map[string]interface{}) ([]Action, error) {
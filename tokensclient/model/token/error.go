@@ -24,6 +24,8 @@ const (
 	TokenContextNotActiveError               = "tok-ctx-not-active"
 	TokenContextNotFoundError                = "tok-ctx-not-found"
 	TokenContextAlreadyExists                = "tok-ctx-already-exists"
+	TokenErrorProcessVarNotFound             = "tok-process-var-not-found-err"
+	TokenErrorProcessVarType                 = "tok-process-var-type-err"
 )
 
 type TokErrorInfo struct {
@@ -49,6 +51,8 @@ var TokErrorTextMapping = map[string]TokErrorInfo{
 	TokenContextNotActiveError:               {StatusCode: http.StatusBadRequest, Code: TokenContextNotActiveError, Text: "token context not active"},
 	TokenExpiredError:                        {StatusCode: http.StatusConflict, Code: TokenExpiredError, Text: "Il codice indicato risulta scaduto."},
 	TokenNotFoundError:                       {StatusCode: http.StatusNotFound, Code: TokenNotFoundError, Text: "Codice a bruciatura non presente a sistema."}, // "token not found"
+	TokenErrorProcessVarNotFound:             {StatusCode: http.StatusInternalServerError, Code: TokenErrorProcessVarNotFound, Text: "process var not found"},
+	TokenErrorProcessVarType:                 {StatusCode: http.StatusInternalServerError, Code: TokenErrorProcessVarType, Text: "process var type mismatch"},
 }
 
 type TokError struct {
@@ -0,0 +1,312 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ProcessVarTypeAny     = ""
+	ProcessVarTypeString  = "string"
+	ProcessVarTypeInt     = "int"
+	ProcessVarTypeDecimal = "decimal"
+	ProcessVarTypeBool    = "bool"
+	ProcessVarTypeTime    = "time"
+)
+
+// ProcessVarTimeLayouts the layouts tried, in order, when a time is requested without an explicit layout.
+var ProcessVarTimeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"20060102150405",
+	"20060102",
+}
+
+func (pv ProcessVars) Has(n string) bool {
+	_, ok := pv[n]
+	return ok
+}
+
+func (pv ProcessVars) GetString(n string) (string, error) {
+	v, ok := pv[n]
+	if !ok {
+		return "", newProcessVarNotFoundError(n)
+	}
+
+	return processVarToString(n, v)
+}
+
+func (pv ProcessVars) GetInt64(n string) (int64, error) {
+	v, ok := pv[n]
+	if !ok {
+		return 0, newProcessVarNotFoundError(n)
+	}
+
+	return processVarToInt64(n, v)
+}
+
+func (pv ProcessVars) GetDecimal(n string) (decimal.Decimal, error) {
+	v, ok := pv[n]
+	if !ok {
+		return decimal.Zero, newProcessVarNotFoundError(n)
+	}
+
+	return processVarToDecimal(n, v)
+}
+
+func (pv ProcessVars) GetBool(n string) (bool, error) {
+	v, ok := pv[n]
+	if !ok {
+		return false, newProcessVarNotFoundError(n)
+	}
+
+	return processVarToBool(n, v)
+}
+
+// GetTime parses the variable with the given layouts. If no layout is provided the ProcessVarTimeLayouts are tried in order.
+func (pv ProcessVars) GetTime(n string, layouts ...string) (time.Time, error) {
+	v, ok := pv[n]
+	if !ok {
+		return time.Time{}, newProcessVarNotFoundError(n)
+	}
+
+	return processVarToTime(n, v, layouts...)
+}
+
+func (pv ProcessVars) GetStringOrDefault(n string, defaultValue string) string {
+	s, err := pv.GetString(n)
+	if err != nil {
+		return defaultValue
+	}
+
+	return s
+}
+
+func (pv ProcessVars) GetInt64OrDefault(n string, defaultValue int64) int64 {
+	i, err := pv.GetInt64(n)
+	if err != nil {
+		return defaultValue
+	}
+
+	return i
+}
+
+func (pv ProcessVars) GetBoolOrDefault(n string, defaultValue bool) bool {
+	b, err := pv.GetBool(n)
+	if err != nil {
+		return defaultValue
+	}
+
+	return b
+}
+
+// Decode fills the struct pointed by target using the json tags of its fields.
+func (pv ProcessVars) Decode(target interface{}) error {
+	b, err := json.Marshal(pv)
+	if err != nil {
+		return NewTokError(TokenErrorProcessVarType, err.Error())
+	}
+
+	err = json.Unmarshal(b, target)
+	if err != nil {
+		return NewTokError(TokenErrorProcessVarType, err.Error())
+	}
+
+	return nil
+}
+
+func processVarToString(n string, v interface{}) (string, error) {
+	switch tv := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return tv, nil
+	case json.Number:
+		return tv.String(), nil
+	case float64:
+		return strconv.FormatFloat(tv, 'f', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(tv), 'f', -1, 32), nil
+	case int, int32, int64, bool:
+		return fmt.Sprint(tv), nil
+	}
+
+	return "", newProcessVarTypeError(n, v, ProcessVarTypeString)
+}
+
+func processVarToInt64(n string, v interface{}) (int64, error) {
+	switch tv := v.(type) {
+	case int:
+		return int64(tv), nil
+	case int32:
+		return int64(tv), nil
+	case int64:
+		return tv, nil
+	case float32:
+		if float32(int64(tv)) == tv {
+			return int64(tv), nil
+		}
+	case float64:
+		if float64(int64(tv)) == tv {
+			return int64(tv), nil
+		}
+	case json.Number:
+		if i, err := tv.Int64(); err == nil {
+			return i, nil
+		}
+	case string:
+		if i, err := strconv.ParseInt(strings.TrimSpace(tv), 10, 64); err == nil {
+			return i, nil
+		}
+	}
+
+	return 0, newProcessVarTypeError(n, v, ProcessVarTypeInt)
+}
+
+func processVarToDecimal(n string, v interface{}) (decimal.Decimal, error) {
+	switch tv := v.(type) {
+	case int:
+		return decimal.NewFromInt(int64(tv)), nil
+	case int32:
+		return decimal.NewFromInt32(tv), nil
+	case int64:
+		return decimal.NewFromInt(tv), nil
+	case float32:
+		return decimal.NewFromFloat32(tv), nil
+	case float64:
+		return decimal.NewFromFloat(tv), nil
+	case json.Number:
+		if d, err := decimal.NewFromString(tv.String()); err == nil {
+			return d, nil
+		}
+	case string:
+		// Allow the comma as decimal separator as it is common in the values coming from the frontends.
+		if d, err := decimal.NewFromString(strings.Replace(strings.TrimSpace(tv), ",", ".", 1)); err == nil {
+			return d, nil
+		}
+	}
+
+	return decimal.Zero, newProcessVarTypeError(n, v, ProcessVarTypeDecimal)
+}
+
+func processVarToBool(n string, v interface{}) (bool, error) {
+	switch tv := v.(type) {
+	case bool:
+		return tv, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(tv)) {
+		case "true", "y", "yes", "1":
+			return true, nil
+		case "false", "n", "no", "0":
+			return false, nil
+		}
+	}
+
+	return false, newProcessVarTypeError(n, v, ProcessVarTypeBool)
+}
+
+func processVarToTime(n string, v interface{}, layouts ...string) (time.Time, error) {
+	switch tv := v.(type) {
+	case time.Time:
+		return tv, nil
+	case string:
+		if len(layouts) == 0 {
+			layouts = ProcessVarTimeLayouts
+		}
+
+		for _, l := range layouts {
+			if tm, err := time.Parse(l, tv); err == nil {
+				return tm, nil
+			}
+		}
+	}
+
+	return time.Time{}, newProcessVarTypeError(n, v, ProcessVarTypeTime)
+}
+
+func newProcessVarNotFoundError(n string) error {
+	return NewTokError(TokenErrorProcessVarNotFound, fmt.Sprintf("process var %s not found", n))
+}
+
+func newProcessVarTypeError(n string, v interface{}, typ string) error {
+	return NewTokError(TokenErrorProcessVarType, fmt.Sprintf("process var %s of type %T cannot be converted to %s", n, v, typ))
+}
+
+type ProcessVarsSchema map[string]ProcessVarDefinition
+
+// ProcessVarsSchema collects the process var definitions of all the transitions of the state machine. Definitions without a type
+// are included as well and accept any value.
+func (ctx *TokenContext) ProcessVarsSchema() ProcessVarsSchema {
+	schema := make(ProcessVarsSchema)
+
+	add := func(trs []Transition) {
+		for _, tr := range trs {
+			for _, pvd := range tr.ProcessVarDefinitions {
+				if d, ok := schema[pvd.Name]; ok && d.Type != ProcessVarTypeAny {
+					continue
+				}
+				schema[pvd.Name] = pvd
+			}
+		}
+	}
+
+	for _, s := range ctx.StateMachine.States {
+		add(s.OutTransitions)
+	}
+	add(ctx.StateMachine.CatchTransitions)
+
+	return schema
+}
+
+// Validate checks the type of the vars declared in the schema. Vars not in the schema and vars not present are not considered
+// since the set of vars depends on the path followed by the token.
+func (schema ProcessVarsSchema) Validate(pv ProcessVars) error {
+
+	var errs []string
+	for n, v := range pv {
+		d, ok := schema[n]
+		if !ok {
+			continue
+		}
+
+		var err error
+		switch d.Type {
+		case ProcessVarTypeAny:
+		case ProcessVarTypeString:
+			if _, ok := v.(string); !ok {
+				err = newProcessVarTypeError(n, v, ProcessVarTypeString)
+			}
+		case ProcessVarTypeInt:
+			_, err = processVarToInt64(n, v)
+		case ProcessVarTypeDecimal:
+			_, err = processVarToDecimal(n, v)
+		case ProcessVarTypeBool:
+			_, err = processVarToBool(n, v)
+		case ProcessVarTypeTime:
+			if d.Format != "" {
+				_, err = processVarToTime(n, v, d.Format)
+			} else {
+				_, err = processVarToTime(n, v)
+			}
+		default:
+			err = NewTokError(TokenErrorContextDefinition, fmt.Sprintf("process var %s has unsupported type %s", n, d.Type))
+		}
+
+		if err != nil {
+			errs = append(errs, err.(*TokError).Description)
+		}
+	}
+
+	if len(errs) > 0 {
+		return NewTokError(TokenErrorProcessVarType, strings.Join(errs, "; "))
+	}
+
+	return nil
+}
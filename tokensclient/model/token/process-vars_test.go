@@ -0,0 +1,81 @@
+package token_test
+
+import (
+	"encoding/json"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestProcessVars(t *testing.T) {
+
+	var pv token.ProcessVars
+	err := json.Unmarshal([]byte(`{"cf": "MPRMLS62S21G337J", "num": 12, "amount": "10,50", "flag": "Y", "expiry-ts": "2023-04-30T10:00:00Z", "dt": "20230430"}`), &pv)
+	require.NoError(t, err)
+
+	s, err := pv.GetString("cf")
+	require.NoError(t, err)
+	require.Equal(t, "MPRMLS62S21G337J", s)
+
+	i, err := pv.GetInt64("num")
+	require.NoError(t, err)
+	require.Equal(t, int64(12), i)
+
+	d, err := pv.GetDecimal("amount")
+	require.NoError(t, err)
+	require.Equal(t, "10.5", d.String())
+
+	b, err := pv.GetBool("flag")
+	require.NoError(t, err)
+	require.True(t, b)
+
+	tm, err := pv.GetTime("expiry-ts")
+	require.NoError(t, err)
+	require.Equal(t, 10, tm.Hour())
+
+	tm, err = pv.GetTime("dt", "20060102")
+	require.NoError(t, err)
+	require.Equal(t, time.April, tm.Month())
+
+	_, err = pv.GetInt64("cf")
+	require.Error(t, err)
+
+	_, err = pv.GetString("missing")
+	require.Error(t, err)
+	require.Equal(t, int64(7), pv.GetInt64OrDefault("missing", 7))
+
+	var target struct {
+		Cf  string `json:"cf"`
+		Num int    `json:"num"`
+	}
+	err = pv.Decode(&target)
+	require.NoError(t, err)
+	require.Equal(t, 12, target.Num)
+
+	ctx := token.TokenContext{
+		StateMachine: token.StateMachine{
+			States: []token.StateDefinition{
+				{
+					Code: token.StartEndState,
+					OutTransitions: []token.Transition{
+						{
+							Name: "creazione",
+							ProcessVarDefinitions: []token.ProcessVarDefinition{
+								{Name: "num", Type: token.ProcessVarTypeInt},
+								{Name: "cf", Type: token.ProcessVarTypeString},
+								{Name: "dt", Type: token.ProcessVarTypeTime, Format: "20060102"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	schema := ctx.ProcessVarsSchema()
+	require.NoError(t, schema.Validate(pv))
+
+	pv["num"] = "not-a-number"
+	require.Error(t, schema.Validate(pv))
+}
@@ -59,6 +59,8 @@ type ProcessVarDefinition struct {
 	Name        string `yaml:"name,omitempty" mapstructure:"name,omitempty" json:"name,omitempty"`
 	Description string `yaml:"description,omitempty" mapstructure:"description,omitempty" json:"description,omitempty"`
 	Value       string `yaml:"value,omitempty" mapstructure:"value,omitempty" json:"value,omitempty"`
+	Type        string `yaml:"type,omitempty" mapstructure:"type,omitempty" json:"type,omitempty"`
+	Format      string `yaml:"format,omitempty" mapstructure:"format,omitempty" json:"format,omitempty"`
 }
 
 type StateDefinition struct {
@@ -71,6 +73,11 @@ type StateDefinition struct {
 	Help           CodeDescriptionPair `yaml:"help,omitempty" mapstructure:"help,omitempty" json:"help,omitempty"`
 }
 
+// IsFinal a final or expired state: the token cannot be used any more.
+func (sd *StateDefinition) IsFinal() bool {
+	return sd.StateType == StateFinal || sd.StateType == StateExpired
+}
+
 type Rule struct {
 	Expression string              `yaml:"expr,omitempty" mapstructure:"expr,omitempty" json:"expr,omitempty"`
 	Help       CodeDescriptionPair `yaml:"help,omitempty" mapstructure:"help,omitempty" json:"help,omitempty"`
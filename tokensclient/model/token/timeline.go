@@ -0,0 +1,218 @@
+package token
+
+import (
+	"github.com/rs/zerolog/log"
+	"sync"
+	"time"
+)
+
+const (
+	TimelineDateLayout      = "20060102"
+	TimelineTimestampLayout = "20060102150405"
+)
+
+// timelineLayouts the accepted formats of start and end dates. The first one is the date only one, the others allow intra-day boundaries.
+// Layouts without an explicit offset are interpreted in the location of the timeline.
+var timelineLayouts = []string{
+	TimelineDateLayout,
+	TimelineTimestampLayout,
+	"200601021504",
+	"2006-01-02",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+}
+
+type Timeline struct {
+	StartDate      string `yaml:"start-date,omitempty" mapstructure:"start-date,omitempty" json:"start-date,omitempty"`
+	EndDate        string `yaml:"end-date,omitempty" mapstructure:"end-date,omitempty" json:"end-date,omitempty"`
+	ExpirationMode string `yaml:"expiration-mode,omitempty" mapstructure:"expiration-mode,omitempty" json:"expiration-mode,omitempty"`
+	Location       string `yaml:"location,omitempty" mapstructure:"location,omitempty" json:"location,omitempty"`
+}
+
+// locations the parsed locations by name: loading one reads the tz database.
+var locations sync.Map
+
+// Loc returns the location of the timeline, the local zone of the process if not set. An invalid location is an error.
+func (tl *Timeline) Loc() (*time.Location, error) {
+	if tl.Location == "" {
+		return time.Local, nil
+	}
+
+	if loc, ok := locations.Load(tl.Location); ok {
+		return loc.(*time.Location), nil
+	}
+
+	loc, err := time.LoadLocation(tl.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	locations.Store(tl.Location, loc)
+	return loc, nil
+}
+
+// Start returns the instant the timeline starts. If not set it is the zero time: the timeline has always been started.
+func (tl *Timeline) Start() (time.Time, error) {
+	if tl.StartDate == "" {
+		return time.Time{}, nil
+	}
+
+	loc, err := tl.Loc()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	tm, _, err := parseTimelineBoundary(tl.StartDate, loc)
+	return tm, err
+}
+
+// End returns the instant the timeline ends (excluded). A date only end date includes the whole day so the timeline ends
+// at midnight of the following day. If not set it is the zero time: the timeline is over, as it used to be with the plain
+// comparison of the dates.
+func (tl *Timeline) End() (time.Time, error) {
+	if tl.EndDate == "" {
+		return time.Time{}, nil
+	}
+
+	loc, err := tl.Loc()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	tm, dateOnly, err := parseTimelineBoundary(tl.EndDate, loc)
+	if err != nil {
+		return tm, err
+	}
+
+	if dateOnly {
+		tm = tm.AddDate(0, 0, 1)
+	}
+
+	return tm, nil
+}
+
+func (tl *Timeline) IsOver() bool {
+	return tl.IsOverAt(time.Now())
+}
+
+func (tl *Timeline) IsOverAt(asOf time.Time) bool {
+	end, err := tl.End()
+	if err != nil {
+		return false
+	}
+
+	return !asOf.Before(end)
+}
+
+func (tl *Timeline) IsNotStartedYet() bool {
+	return tl.IsNotStartedYetAt(time.Now())
+}
+
+func (tl *Timeline) IsNotStartedYetAt(asOf time.Time) bool {
+	start, err := tl.Start()
+	if err != nil {
+		return false
+	}
+
+	return asOf.Before(start)
+}
+
+func (tl *Timeline) IsInRange() bool {
+	return tl.IsInRangeAt(time.Now())
+}
+
+func (tl *Timeline) IsInRangeAt(asOf time.Time) bool {
+	start, err := tl.Start()
+	if err != nil {
+		return false
+	}
+
+	end, err := tl.End()
+	if err != nil {
+		return false
+	}
+
+	return !asOf.Before(start) && asOf.Before(end)
+}
+
+func (tl *Timeline) NumberOfDays() (int, error) {
+	loc, err := tl.Loc()
+	if err != nil {
+		return -1, err
+	}
+
+	end, _, err := parseTimelineBoundary(tl.EndDate, loc)
+	if err != nil {
+		return -1, err
+	}
+
+	start, _, err := parseTimelineBoundary(tl.StartDate, loc)
+	if err != nil {
+		return -1, err
+	}
+
+	// Calendar days between the two dates, irrespective of the time of the day and of DST changes.
+	endDay := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	startDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	numDays := endDay.Sub(startDay).Hours() / 24.0
+	return int(numDays), nil
+}
+
+func (tl *Timeline) Valid() bool {
+	return tl.ValidAt(time.Now())
+}
+
+// ValidAt validates the timeline. Empty start and end dates default to the day of asOf.
+func (tl *Timeline) ValidAt(asOf time.Time) bool {
+
+	const semLogContext = "context timeline validation"
+
+	loc, err := tl.Loc()
+	if err != nil {
+		log.Error().Err(err).Str("location", tl.Location).Msg(semLogContext + " invalid location")
+		return false
+	}
+
+	if tl.StartDate == "" {
+		tl.StartDate = asOf.In(loc).Format(TimelineDateLayout)
+	}
+
+	start, err := tl.Start()
+	if err != nil {
+		log.Error().Err(err).Str("start-date", tl.StartDate).Str("end-date", tl.EndDate).Msg(semLogContext + " invalid start-date format")
+		return false
+	}
+
+	if tl.EndDate == "" {
+		tl.EndDate = asOf.In(loc).Format(TimelineDateLayout)
+	}
+
+	end, err := tl.End()
+	if err != nil {
+		log.Error().Err(err).Str("start-date", tl.StartDate).Str("end-date", tl.EndDate).Msg(semLogContext + " invalid end-date format")
+		return false
+	}
+
+	if !start.Before(end) {
+		log.Error().Str("start-date", tl.StartDate).Str("end-date", tl.EndDate).Msg(semLogContext + " invalid range interval")
+		return false
+	}
+
+	return true
+}
+
+// parseTimelineBoundary parses a start or end date in the given location and tells if the value carried the date only.
+func parseTimelineBoundary(s string, loc *time.Location) (time.Time, bool, error) {
+	var err error
+	var tm time.Time
+	for i, l := range timelineLayouts {
+		tm, err = time.ParseInLocation(l, s, loc)
+		if err == nil {
+			return tm, i == 0 || l == "2006-01-02", nil
+		}
+	}
+
+	// Report the error of the canonical layout.
+	_, err = time.ParseInLocation(TimelineDateLayout, s, loc)
+	return time.Time{}, false, err
+}
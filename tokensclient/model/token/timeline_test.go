@@ -0,0 +1,74 @@
+package token_test
+
+import (
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestTimeline(t *testing.T) {
+
+	now := time.Date(2023, 4, 30, 22, 30, 0, 0, time.UTC)
+
+	rome := token.Timeline{StartDate: "20230101", EndDate: "20230430", Location: "Europe/Rome"}
+	require.True(t, rome.ValidAt(now))
+	require.True(t, rome.IsOverAt(now))
+	require.False(t, rome.IsInRangeAt(now))
+
+	utc := token.Timeline{StartDate: "20230101", EndDate: "20230430", Location: "UTC"}
+	require.False(t, utc.IsOverAt(now))
+	require.True(t, utc.IsInRangeAt(now))
+
+	n, err := utc.NumberOfDays()
+	require.NoError(t, err)
+	require.Equal(t, 119, n)
+
+	intraDay := token.Timeline{StartDate: "20230430230000", EndDate: "20230501", Location: "UTC"}
+	require.True(t, intraDay.IsNotStartedYetAt(now))
+
+	tok := token.Token{Id: "TOK1", Events: []token.Event{{ExpiryTs: "20230430"}}}
+	require.True(t, (&token.TokenContext{Timeline: rome}).IsTokenExpiredAt(&tok, now))
+	require.False(t, (&token.TokenContext{Timeline: utc}).IsTokenExpiredAt(&tok, now))
+
+	tok.Events[0].ExpiryTs = "2023-04-30T22:00:00Z"
+	require.True(t, tok.IsExpiredAt(token.ExpirationModeTimestamp, nil, now))
+
+	require.False(t, (&token.Timeline{StartDate: "20230501", EndDate: "20230430"}).ValidAt(now))
+
+	// empty dates keep their meaning: no start date is started, no end date is over.
+	require.False(t, (&token.Timeline{EndDate: "20230501"}).IsNotStartedYetAt(now))
+	require.True(t, (&token.Timeline{EndDate: "20230501"}).IsInRangeAt(now))
+	require.True(t, (&token.Timeline{StartDate: "20230101"}).IsOverAt(now))
+
+	invalid := token.Timeline{StartDate: "20230101", EndDate: "20230501", Location: "Europe/Nowhere"}
+	_, err = invalid.Loc()
+	require.Error(t, err)
+	require.False(t, invalid.ValidAt(now))
+	require.False(t, invalid.IsInRangeAt(now))
+	require.True(t, (&token.TokenContext{Timeline: invalid}).IsTokenExpiredAt(&tok, now))
+
+	require.True(t, utc.IsOverAt(now.Add(time.Hour*2)))
+
+	asOf := time.Date(2023, 4, 30, 12, 0, 0, 0, time.UTC)
+	require.True(t, rome.IsInRangeAt(asOf))
+	require.False(t, tok.IsExpiredAt(token.ExpirationModeTimestamp, nil, asOf))
+}
+
+func TestTokenExpiredAt(t *testing.T) {
+
+	// expired on the 10th, renewed on the 15th until the end of the month.
+	tok := token.Token{Id: "TOK1", Events: []token.Event{
+		{Ts: "2023-04-01T09:00:00Z", ExpiryTs: "20230410"},
+		{Ts: "2023-04-15T09:00:00Z", ExpiryTs: "20230430"},
+	}}
+	ctx := token.TokenContext{Timeline: token.Timeline{Location: "UTC"}}
+
+	require.False(t, ctx.IsTokenExpiredAt(&tok, time.Date(2023, 4, 5, 0, 0, 0, 0, time.UTC)))
+	require.True(t, ctx.IsTokenExpiredAt(&tok, time.Date(2023, 4, 12, 0, 0, 0, 0, time.UTC)))
+	require.False(t, ctx.IsTokenExpiredAt(&tok, time.Date(2023, 4, 20, 0, 0, 0, 0, time.UTC)))
+	require.True(t, ctx.IsTokenExpiredAt(&tok, time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)))
+
+	// before the first event the token did not exist.
+	require.False(t, ctx.IsTokenExpiredAt(&tok, time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)))
+}
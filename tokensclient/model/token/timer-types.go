@@ -3,6 +3,7 @@ package token
 import (
 	"encoding/json"
 	"strings"
+	"time"
 )
 
 const (
@@ -36,6 +37,39 @@ func (timer *Timer) MarkAsOutdated() {
 	timer.Outdated = true
 }
 
+// ExpiresTime parses the expiration of the timer. RFC3339 is the format written by the client, the timeline formats are accepted too
+// and interpreted in loc (local zone if nil).
+func (timer *Timer) ExpiresTime(loc *time.Location) (time.Time, error) {
+	if tm, err := time.Parse(time.RFC3339, timer.Expires); err == nil {
+		return tm, nil
+	}
+
+	if loc == nil {
+		loc = time.Local
+	}
+
+	tm, _, err := parseTimelineBoundary(timer.Expires, loc)
+	return tm, err
+}
+
+func (timer *Timer) SetExpires(tm time.Time) {
+	timer.Expires = tm.Format(time.RFC3339)
+}
+
+// IsDueAt the timer is active and expired as of the given instant.
+func (timer *Timer) IsDueAt(asOf time.Time) bool {
+	if timer.Status != "" && timer.Status != StatusTimerActive {
+		return false
+	}
+
+	tm, err := timer.ExpiresTime(nil)
+	if err != nil {
+		return false
+	}
+
+	return !asOf.Before(tm)
+}
+
 func (timer *Timer) ToJSON() ([]byte, error) {
 	return json.Marshal(timer)
 }
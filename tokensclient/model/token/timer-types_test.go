@@ -0,0 +1,26 @@
+package token_test
+
+import (
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestTimerExpires(t *testing.T) {
+
+	asOf := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tm := token.Timer{Id: "TM1", Status: token.StatusTimerActive}
+	tm.SetExpires(asOf.Add(time.Minute))
+	require.False(t, tm.IsDueAt(asOf))
+	require.True(t, tm.IsDueAt(asOf.Add(time.Minute)))
+
+	tm.Expires = "20230501"
+	exp, err := tm.ExpiresTime(time.UTC)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), exp)
+
+	tm.Status = token.StatusTimerProcessed
+	require.False(t, tm.IsDueAt(asOf))
+}
@@ -0,0 +1,179 @@
+package token
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// AuditRecord a line of the audit trail of a token: the diff of an event qualified by the token.
+type AuditRecord struct {
+	CtxId     string `yaml:"ctx-id,omitempty" mapstructure:"ctx-id,omitempty" json:"ctx-id,omitempty"`
+	TokenId   string `yaml:"token-id,omitempty" mapstructure:"token-id,omitempty" json:"token-id,omitempty"`
+	EventDiff `mapstructure:",squash"  yaml:",inline"`
+}
+
+func (tok *Token) AuditTrail() []AuditRecord {
+	var recs []AuditRecord
+	for _, d := range tok.History() {
+		recs = append(recs, AuditRecord{CtxId: tok.CtxId, TokenId: tok.Id, EventDiff: d})
+	}
+
+	return recs
+}
+
+// WriteAuditJSONL writes the audit trail as JSON lines, one record per event.
+func (tok *Token) WriteAuditJSONL(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, r := range tok.AuditTrail() {
+		if err := enc.Encode(&r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var AuditCSVHeader = []string{
+	"ctx-id", "token-id", "index", "ts", "event", "type", "request-id", "from-state", "to-state", "pending", "expiry-ts",
+	"vars-added", "vars-changed", "vars-removed", "bearers-added", "bearers-removed", "bearers-changed", "timers-created", "timers-outdated", "actions",
+}
+
+// WriteAuditCSV writes the audit trail as CSV with the AuditCSVHeader columns. Multi valued columns are ';' separated.
+func (tok *Token) WriteAuditCSV(w io.Writer, withHeader bool) error {
+	cw := csv.NewWriter(w)
+	if withHeader {
+		if err := cw.Write(AuditCSVHeader); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range tok.AuditTrail() {
+		rec := []string{
+			r.CtxId, r.TokenId, strconv.Itoa(r.Index), r.Ts, r.Name, string(r.Typ), r.RequestId, r.FromState, r.ToState, strconv.FormatBool(r.Pending), r.ExpiryTs,
+			formatVarChanges(r.VarsAdded), formatVarChanges(r.VarsChanged), formatVarChanges(r.VarsRemoved),
+			formatBearers(r.BearersAdded), formatBearers(r.BearersRemoved), formatBearerChanges(r.BearersChanged),
+			strings.Join(r.TimersCreated, ";"), strings.Join(r.TimersOutdated, ";"), formatActions(r.Actions),
+		}
+
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteAuditReport writes a human readable account of the history of the token.
+func (tok *Token) WriteAuditReport(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("token %s (context %s): %d events, current state %s\n", tok.Id, tok.CtxId, len(tok.Events), tok.currentStateOrUnknown()))
+
+	for _, d := range tok.History() {
+		sb.WriteString(fmt.Sprintf("\n#%d %s %s [%s]", d.Index, d.Ts, d.Name, d.Typ))
+		if d.RequestId != "" {
+			sb.WriteString(" request " + d.RequestId)
+		}
+		sb.WriteString("\n")
+
+		if d.StateChanged() {
+			sb.WriteString(fmt.Sprintf("  state: %s -> %s", d.FromState, d.ToState))
+		} else {
+			sb.WriteString(fmt.Sprintf("  state: %s (unchanged)", d.ToState))
+		}
+		if d.Pending {
+			sb.WriteString(" pending")
+		}
+		sb.WriteString("\n")
+
+		if d.ExpiryTs != "" {
+			sb.WriteString("  expiry: " + d.ExpiryTs + "\n")
+		}
+
+		for _, v := range d.VarsAdded {
+			sb.WriteString(fmt.Sprintf("  + var %s = %v\n", v.Name, v.NewValue))
+		}
+		for _, v := range d.VarsChanged {
+			sb.WriteString(fmt.Sprintf("  ~ var %s: %v -> %v\n", v.Name, v.OldValue, v.NewValue))
+		}
+		for _, v := range d.VarsRemoved {
+			sb.WriteString(fmt.Sprintf("  - var %s (was %v)\n", v.Name, v.OldValue))
+		}
+		for _, b := range d.BearersAdded {
+			sb.WriteString(fmt.Sprintf("  + bearer %s as %s\n", b.Id, b.Role))
+		}
+		for _, b := range d.BearersChanged {
+			sb.WriteString(fmt.Sprintf("  ~ bearer %s: %s -> %s\n", b.Id, b.OldRole, b.NewRole))
+		}
+		for _, b := range d.BearersRemoved {
+			sb.WriteString(fmt.Sprintf("  - bearer %s (was %s)\n", b.Id, b.Role))
+		}
+		for _, id := range d.TimersCreated {
+			sb.WriteString("  + timer " + id + "\n")
+		}
+		for _, id := range d.TimersOutdated {
+			sb.WriteString("  - timer " + id + " outdated\n")
+		}
+		for _, a := range d.Actions {
+			sb.WriteString(fmt.Sprintf("  action %s [%s]\n", a.ActionId, a.ActionType))
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (tok *Token) currentStateOrUnknown() string {
+	if len(tok.Events) == 0 {
+		return "unknown"
+	}
+
+	return tok.Events[len(tok.Events)-1].State.Code
+}
+
+func formatVarChanges(vcs []VarChange) string {
+	var comps []string
+	for _, v := range vcs {
+		switch {
+		case v.OldValue == nil:
+			comps = append(comps, fmt.Sprintf("%s=%v", v.Name, v.NewValue))
+		case v.NewValue == nil:
+			comps = append(comps, fmt.Sprintf("%s=%v", v.Name, v.OldValue))
+		default:
+			comps = append(comps, fmt.Sprintf("%s=%v->%v", v.Name, v.OldValue, v.NewValue))
+		}
+	}
+
+	return strings.Join(comps, ";")
+}
+
+func formatBearers(brs []BearerRef) string {
+	var comps []string
+	for _, b := range brs {
+		comps = append(comps, b.Id+":"+b.Role)
+	}
+
+	return strings.Join(comps, ";")
+}
+
+func formatBearerChanges(bcs []BearerRoleChange) string {
+	var comps []string
+	for _, b := range bcs {
+		comps = append(comps, b.Id+":"+b.OldRole+"->"+b.NewRole)
+	}
+
+	return strings.Join(comps, ";")
+}
+
+func formatActions(acts []Action) string {
+	var comps []string
+	for _, a := range acts {
+		comps = append(comps, a.ActionId)
+	}
+
+	return strings.Join(comps, ";")
+}
@@ -0,0 +1,162 @@
+package token
+
+import (
+	"reflect"
+	"sort"
+)
+
+type VarChange struct {
+	Name     string      `yaml:"name,omitempty" mapstructure:"name,omitempty" json:"name,omitempty"`
+	OldValue interface{} `yaml:"old-value,omitempty" mapstructure:"old-value,omitempty" json:"old-value,omitempty"`
+	NewValue interface{} `yaml:"new-value,omitempty" mapstructure:"new-value,omitempty" json:"new-value,omitempty"`
+}
+
+type BearerRoleChange struct {
+	Id      string `yaml:"id,omitempty" mapstructure:"id,omitempty" json:"id,omitempty"`
+	OldRole string `yaml:"old-role,omitempty" mapstructure:"old-role,omitempty" json:"old-role,omitempty"`
+	NewRole string `yaml:"new-role,omitempty" mapstructure:"new-role,omitempty" json:"new-role,omitempty"`
+}
+
+// EventDiff what an event changed with respect to the previous one. Timers of the previous event are reported as outdated since
+// every new event supersedes them.
+type EventDiff struct {
+	Index          int                `yaml:"index" mapstructure:"index" json:"index"`
+	RequestId      string             `yaml:"request-id,omitempty" mapstructure:"request-id,omitempty" json:"request-id,omitempty"`
+	Name           string             `yaml:"name,omitempty" mapstructure:"name,omitempty" json:"name,omitempty"`
+	Typ            EventType          `yaml:"type,omitempty" mapstructure:"type,omitempty" json:"type,omitempty"`
+	Ts             string             `yaml:"ts,omitempty" mapstructure:"ts,omitempty" json:"ts,omitempty"`
+	FromState      string             `yaml:"from-state,omitempty" mapstructure:"from-state,omitempty" json:"from-state,omitempty"`
+	ToState        string             `yaml:"to-state,omitempty" mapstructure:"to-state,omitempty" json:"to-state,omitempty"`
+	Pending        bool               `yaml:"pending,omitempty" mapstructure:"pending,omitempty" json:"pending,omitempty"`
+	ExpiryTs       string             `yaml:"expiry-ts,omitempty" mapstructure:"expiry-ts,omitempty" json:"expiry-ts,omitempty"`
+	VarsAdded      []VarChange        `yaml:"vars-added,omitempty" mapstructure:"vars-added,omitempty" json:"vars-added,omitempty"`
+	VarsChanged    []VarChange        `yaml:"vars-changed,omitempty" mapstructure:"vars-changed,omitempty" json:"vars-changed,omitempty"`
+	VarsRemoved    []VarChange        `yaml:"vars-removed,omitempty" mapstructure:"vars-removed,omitempty" json:"vars-removed,omitempty"`
+	BearersAdded   []BearerRef        `yaml:"bearers-added,omitempty" mapstructure:"bearers-added,omitempty" json:"bearers-added,omitempty"`
+	BearersRemoved []BearerRef        `yaml:"bearers-removed,omitempty" mapstructure:"bearers-removed,omitempty" json:"bearers-removed,omitempty"`
+	BearersChanged []BearerRoleChange `yaml:"bearers-changed,omitempty" mapstructure:"bearers-changed,omitempty" json:"bearers-changed,omitempty"`
+	TimersCreated  []string           `yaml:"timers-created,omitempty" mapstructure:"timers-created,omitempty" json:"timers-created,omitempty"`
+	TimersOutdated []string           `yaml:"timers-outdated,omitempty" mapstructure:"timers-outdated,omitempty" json:"timers-outdated,omitempty"`
+	Actions        []Action           `yaml:"actions,omitempty" mapstructure:"actions,omitempty" json:"actions,omitempty"`
+}
+
+func (d *EventDiff) StateChanged() bool {
+	return d.FromState != d.ToState
+}
+
+// DiffEvents computes the changes brought by cur. prev is nil for the first event of the token.
+func DiffEvents(ndx int, prev *Event, cur *Event) EventDiff {
+	d := EventDiff{
+		Index:     ndx,
+		RequestId: cur.RequestId,
+		Name:      cur.Name,
+		Typ:       cur.Typ,
+		Ts:        cur.Ts,
+		FromState: StartEndState,
+		ToState:   cur.State.Code,
+		Pending:   cur.State.Pending,
+		ExpiryTs:  cur.ExpiryTs,
+		Actions:   cur.Actions,
+	}
+
+	var prevVars ProcessVars
+	var prevBearers []BearerRef
+	var prevTimers []Timer
+	if prev != nil {
+		d.FromState = prev.State.Code
+		prevVars = prev.Vars
+		prevBearers = prev.Bearers
+		prevTimers = prev.TimerReferences
+	}
+
+	d.VarsAdded, d.VarsChanged, d.VarsRemoved = diffVars(prevVars, cur.Vars)
+	d.BearersAdded, d.BearersRemoved, d.BearersChanged = diffBearers(prevBearers, cur.Bearers)
+
+	prevTimerIds := make(map[string]struct{})
+	for _, tm := range prevTimers {
+		prevTimerIds[tm.Id] = struct{}{}
+		d.TimersOutdated = append(d.TimersOutdated, tm.Id)
+	}
+
+	for _, tm := range cur.TimerReferences {
+		if _, ok := prevTimerIds[tm.Id]; !ok {
+			d.TimersCreated = append(d.TimersCreated, tm.Id)
+		}
+	}
+
+	return d
+}
+
+// History the diffs of all the events of the token, oldest first.
+func (tok *Token) History() []EventDiff {
+	var diffs []EventDiff
+	for i := range tok.Events {
+		var prev *Event
+		if i > 0 {
+			prev = &tok.Events[i-1]
+		}
+		diffs = append(diffs, DiffEvents(i, prev, &tok.Events[i]))
+	}
+
+	return diffs
+}
+
+func diffVars(prev, cur ProcessVars) ([]VarChange, []VarChange, []VarChange) {
+	var added, changed, removed []VarChange
+	for _, n := range sortedVarNames(cur) {
+		ov, ok := prev[n]
+		switch {
+		case !ok:
+			added = append(added, VarChange{Name: n, NewValue: cur[n]})
+		case !reflect.DeepEqual(ov, cur[n]):
+			changed = append(changed, VarChange{Name: n, OldValue: ov, NewValue: cur[n]})
+		}
+	}
+
+	for _, n := range sortedVarNames(prev) {
+		if _, ok := cur[n]; !ok {
+			removed = append(removed, VarChange{Name: n, OldValue: prev[n]})
+		}
+	}
+
+	return added, changed, removed
+}
+
+func sortedVarNames(pv ProcessVars) []string {
+	names := make([]string, 0, len(pv))
+	for n := range pv {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func diffBearers(prev, cur []BearerRef) ([]BearerRef, []BearerRef, []BearerRoleChange) {
+	var added, removed []BearerRef
+	var changed []BearerRoleChange
+
+	prevRoles := make(map[string]string)
+	for _, b := range prev {
+		prevRoles[b.Id] = b.Role
+	}
+
+	curRoles := make(map[string]string)
+	for _, b := range cur {
+		curRoles[b.Id] = b.Role
+		r, ok := prevRoles[b.Id]
+		switch {
+		case !ok:
+			added = append(added, b)
+		case r != b.Role:
+			changed = append(changed, BearerRoleChange{Id: b.Id, OldRole: r, NewRole: b.Role})
+		}
+	}
+
+	for _, b := range prev {
+		if _, ok := curRoles[b.Id]; !ok {
+			removed = append(removed, b)
+		}
+	}
+
+	return added, removed, changed
+}
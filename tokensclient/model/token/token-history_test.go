@@ -0,0 +1,62 @@
+package token_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+var historyTestToken = token.Token{
+	Id:    "TOK1",
+	CtxId: "BPMGM1",
+	Events: []token.Event{
+		{
+			Name: "creazione", Typ: token.EventTypeCreate, Ts: "2023-05-01T10:00:00Z", State: token.State{Code: "generato"},
+			Vars:            token.ProcessVars{"cf": "MPRMLS62S21G337J", "canale": "web"},
+			Bearers:         []token.BearerRef{{Id: "A-BPMGM1", Role: "primary"}},
+			TimerReferences: []token.Timer{{Id: "TM1", Outdated: true}},
+		},
+		{
+			Name: "uso-ok", Typ: token.EventTypeNext, Ts: "2023-05-02T10:00:00Z", State: token.State{Code: "bruciato"},
+			Vars:    token.ProcessVars{"cf": "MPRMLS62S21G337J", "canale": "app", "importo": 10},
+			Bearers: []token.BearerRef{{Id: "A-BPMGM1", Role: "secondary"}, {Id: "B-BPMGM1", Role: "primary"}},
+			Actions: []token.Action{{ActionId: "notify", ActionType: token.ActionTypeIn}},
+		},
+	},
+}
+
+func TestTokenHistory(t *testing.T) {
+
+	h := historyTestToken.History()
+	require.Len(t, h, 2)
+	require.Equal(t, token.StartEndState, h[0].FromState)
+	require.Len(t, h[0].VarsAdded, 2)
+	require.Equal(t, []string{"TM1"}, h[0].TimersCreated)
+
+	d := h[1]
+	require.True(t, d.StateChanged())
+	require.Equal(t, []token.VarChange{{Name: "importo", NewValue: 10}}, d.VarsAdded)
+	require.Equal(t, []token.VarChange{{Name: "canale", OldValue: "web", NewValue: "app"}}, d.VarsChanged)
+	require.Equal(t, []token.BearerRoleChange{{Id: "A-BPMGM1", OldRole: "primary", NewRole: "secondary"}}, d.BearersChanged)
+	require.Len(t, d.BearersAdded, 1)
+	require.Equal(t, []string{"TM1"}, d.TimersOutdated)
+
+	var buf bytes.Buffer
+	require.NoError(t, historyTestToken.WriteAuditJSONL(&buf))
+	require.Equal(t, 2, strings.Count(buf.String(), "\n"))
+
+	buf.Reset()
+	require.NoError(t, historyTestToken.WriteAuditCSV(&buf, true))
+	recs, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, recs, 3)
+	require.Equal(t, "canale=web->app", recs[2][12])
+
+	buf.Reset()
+	require.NoError(t, historyTestToken.WriteAuditReport(&buf))
+	require.Contains(t, buf.String(), "state: generato -> bruciato")
+	require.Contains(t, buf.String(), "~ bearer A-BPMGM1: primary -> secondary")
+}
@@ -49,35 +49,7 @@ const (
 
 var NilTokenId = ""
 
-/*
-type TokenId string
-
-func (t TokenId) String() string {
-	return fmt.Sprintf("%s:%s:%s", t.CampaignId, t.TokenId, t.CheckDigit)
-}
-*/
-
-// var TokenPatternRegexp = regexp.MustCompile("^([a-zA-Z]{6})\\:([a-zA-Z0-9]{1,16})\\:([a-zA-Z0-9])$")
-
-/*
-var TokenPatternRegExp = regexp.MustCompile("^([a-zA-Z]{6})([a-zA-Z0-9]{16})([a-zA-Z0-9])$")
-
-func ParseTokenId(c string) (TokenId, bool) {
-
-	matches := TokenPatternRegExp.FindAllSubmatch([]byte(c), -1)
-	if len(matches) > 0 {
-		t := TokenId{
-			CampaignId: string(matches[0][1]),
-			TokenId:    string(matches[0][2]),
-			CheckDigit: string(matches[0][3]),
-		}
-		return t, true
-	}
-
-	return TokenId{}, false
-}
-*/
-
+// TokenIdProvider generates the ids of new tokens. Implementations and the parsing and validation of the id formats are in the tokenid package.
 type TokenIdProvider interface {
 	NewId(ctxId string, unique bool, action map[string]interface{}) (string, error)
 }
@@ -260,29 +232,94 @@ func (tok *Token) FindEventIndexByRequestId(reqId string) int {
 	return -1
 }
 
+// IsExpired the token does not know the timeline of its context: date expiries are interpreted in the local zone of the process.
+//
+// Deprecated: use TokenContext.IsTokenExpired that interprets them in the location of the context.
 func (tok *Token) IsExpired(timelineMode string) bool {
+	return tok.IsExpiredInLocation(timelineMode, time.Local)
+}
+
+// IsExpiredInLocation in date mode the expiry date is interpreted in the given location and the token expires at the end of that day.
+func (tok *Token) IsExpiredInLocation(timelineMode string, loc *time.Location) bool {
+	return tok.IsExpiredAt(timelineMode, loc, time.Now())
+}
+
+// IsExpiredAt tells whether the token was expired at the given instant, with the expiry in force at that instant: the one of the
+// last event that had happened by then. Events without a valid timestamp are considered happened.
+func (tok *Token) IsExpiredAt(timelineMode string, loc *time.Location, asOf time.Time) bool {
 	lastEvt := tok.FindLastEventIndex()
 	if lastEvt < 0 {
 		log.Warn().Str(semLogLabelTokenId, tok.Id).Msg("token is empty")
 		return true
 	}
 
-	expTs := tok.Events[lastEvt].ExpiryTs
-	if expTs == "" {
+	evt := tok.findEventIndexAt(asOf)
+	if evt < 0 {
+		// the token did not exist yet.
+		return false
+	}
+
+	expTm, ok, err := tok.Events[evt].expiryTime(tok.Id, timelineMode, loc)
+	if err != nil {
+		return true
+	}
+
+	if !ok {
 		return false
 	}
 
+	return !asOf.Before(expTm)
+}
+
+// findEventIndexAt the index of the last event happened by asOf.
+func (tok *Token) findEventIndexAt(asOf time.Time) int {
+	for i := len(tok.Events) - 1; i >= 0; i-- {
+		ts, err := time.Parse(time.RFC3339, tok.Events[i].Ts)
+		if err != nil || !ts.After(asOf) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// ExpiryTime returns the instant the token expires. The boolean is false if the token has no expiry.
+func (tok *Token) ExpiryTime(timelineMode string, loc *time.Location) (time.Time, bool, error) {
+	lastEvt := tok.FindLastEventIndex()
+	if lastEvt < 0 {
+		return time.Time{}, false, nil
+	}
+
+	return tok.Events[lastEvt].expiryTime(tok.Id, timelineMode, loc)
+}
+
+func (evt *Event) expiryTime(tokId string, timelineMode string, loc *time.Location) (time.Time, bool, error) {
+	expTs := evt.ExpiryTs
+	if expTs == "" {
+		return time.Time{}, false, nil
+	}
+
 	if timelineMode == ExpirationModeTimestamp {
 		expTm, err := time.Parse(time.RFC3339, expTs)
 		if err != nil {
-			log.Error().Err(err).Str(semLogLabelTokenId, tok.Id).Str("expiry-ts", expTs).Msg("invalid token expiry ts")
-			return true
+			log.Error().Err(err).Str(semLogLabelTokenId, tokId).Str("expiry-ts", expTs).Msg("invalid token expiry ts")
+			return time.Time{}, false, err
 		}
 
-		return time.Now().After(expTm)
+		return expTm, true, nil
+	}
+
+	if loc == nil {
+		loc = time.Local
+	}
+
+	expDay, err := time.ParseInLocation(TimelineDateLayout, expTs, loc)
+	if err != nil {
+		log.Error().Err(err).Str(semLogLabelTokenId, tokId).Str("expiry-ts", expTs).Msg("invalid token expiry date")
+		return time.Time{}, false, err
 	}
 
-	return expTs < time.Now().Format("20060102")
+	return expDay.AddDate(0, 0, 1), true, nil
 }
 
 func (tok *Token) Vars() ProcessVars {
@@ -0,0 +1,219 @@
+package token
+
+import (
+	"fmt"
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-common/util/expression"
+	"github.com/rs/zerolog/log"
+	"strings"
+)
+
+const (
+	PropertyValidationRuleRequired = "required"
+)
+
+func (p *Property) IsInScope(scope string) bool {
+	if p.Scope == "" || p.Scope == scope {
+		return true
+	}
+
+	return false
+}
+
+// Validate evaluates the validation rule of the property. The required rule checks the property is present and not empty in the
+// input, any other rule is evaluated as a boolean expression.
+func (p *Property) Validate(eCtx *expression.Context, input map[string]interface{}) (bool, error) {
+	switch p.ValidationRule {
+	case "":
+		return true, nil
+	case PropertyValidationRuleRequired:
+		v, ok := input[p.Name]
+		if !ok || v == nil {
+			return false, nil
+		}
+
+		if s, isString := v.(string); isString && strings.TrimSpace(s) == "" {
+			return false, nil
+		}
+
+		return true, nil
+	}
+
+	return eCtx.BoolEvalOne(p.ValidationRule)
+}
+
+type PropertyDiagnostic struct {
+	Transition string              `yaml:"transition,omitempty" mapstructure:"transition,omitempty" json:"transition,omitempty"`
+	Property   string              `yaml:"property,omitempty" mapstructure:"property,omitempty" json:"property,omitempty"`
+	Rule       string              `yaml:"rule,omitempty" mapstructure:"rule,omitempty" json:"rule,omitempty"`
+	Help       CodeDescriptionPair `yaml:"help,omitempty" mapstructure:"help,omitempty" json:"help,omitempty"`
+	Error      string              `yaml:"error,omitempty" mapstructure:"error,omitempty" json:"error,omitempty"`
+}
+
+// ValidateProperties returns the diagnostics of the properties in scope that do not pass their validation rule.
+func (tr *Transition) ValidateProperties(scope string, eCtx *expression.Context, input map[string]interface{}) []PropertyDiagnostic {
+
+	const semLogContext = "transition::validate-properties"
+
+	var diags []PropertyDiagnostic
+	for _, p := range tr.Properties {
+		if !p.IsInScope(scope) {
+			continue
+		}
+
+		ok, err := p.Validate(eCtx, input)
+		if ok && err == nil {
+			continue
+		}
+
+		d := PropertyDiagnostic{Transition: tr.Name, Property: p.Name, Rule: p.ValidationRule, Help: evalHelp(eCtx, p.Help)}
+		if err != nil {
+			log.Warn().Err(err).Str("transition", tr.Name).Str("property", p.Name).Msg(semLogContext)
+			d.Error = err.Error()
+		}
+
+		if d.Help.Description == "" {
+			d.Help.Description = fmt.Sprintf("property %s is not valid", p.Name)
+		}
+
+		diags = append(diags, d)
+	}
+
+	return diags
+}
+
+// CheckRules evaluates the rules of the transition and returns the index of the first one not satisfied or -1.
+func (tr *Transition) CheckRules(eCtx *expression.Context) (bool, int, error) {
+	if len(tr.Rules) == 0 {
+		return true, -1, nil
+	}
+
+	for i, r := range tr.Rules {
+		ok, err := eCtx.BoolEvalOne(r.Expression)
+		if err != nil {
+			return false, i, err
+		}
+
+		if !ok {
+			return false, i, nil
+		}
+	}
+
+	return true, -1, nil
+}
+
+// FindStateTransitions returns the out transitions of the state followed by the catch transitions of the state machine.
+func (sm *StateMachine) FindStateTransitions(state string) ([]Transition, error) {
+	sd, err := sm.FindStateDefinition(state)
+	if err != nil {
+		return nil, err
+	}
+
+	var trs []Transition
+	trs = append(trs, sd.OutTransitions...)
+	trs = append(trs, sm.CatchTransitions...)
+	return trs, nil
+}
+
+type TransitionsValidation struct {
+	State       string               `yaml:"state,omitempty" mapstructure:"state,omitempty" json:"state,omitempty"`
+	Scope       string               `yaml:"scope,omitempty" mapstructure:"scope,omitempty" json:"scope,omitempty"`
+	Candidates  []string             `yaml:"candidates,omitempty" mapstructure:"candidates,omitempty" json:"candidates,omitempty"`
+	Diagnostics []PropertyDiagnostic `yaml:"diagnostics,omitempty" mapstructure:"diagnostics,omitempty" json:"diagnostics,omitempty"`
+}
+
+func (tv *TransitionsValidation) IsValid() bool {
+	return len(tv.Candidates) > 0 && len(tv.Diagnostics) == 0
+}
+
+// Err maps the validation outcome to the errors the server would return for the same input.
+func (tv *TransitionsValidation) Err() error {
+	if len(tv.Candidates) == 0 {
+		return NewTokError(TokenErrorNotTransitionFound, fmt.Sprintf("no transition found from state %s", tv.State))
+	}
+
+	if len(tv.Diagnostics) > 0 {
+		var sb strings.Builder
+		for i, d := range tv.Diagnostics {
+			if i > 0 {
+				sb.WriteString("; ")
+			}
+			sb.WriteString(d.Help.Description)
+		}
+		return NewTokError(TokenErrorPropertiesValidationEvaluation, sb.String())
+	}
+
+	return nil
+}
+
+// ValidateTransitions determines the transitions from the given state that are candidates for the input and validates their properties.
+// If transitionName is provided only that transition is considered (take-transition semantics), otherwise candidates are the ones whose
+// rules are satisfied. Vars are the process vars of the token, nil for tokens still to be created.
+func (ctx *TokenContext) ValidateTransitions(state string, transitionName string, scope string, vars ProcessVars, input map[string]interface{}) (*TransitionsValidation, error) {
+
+	const semLogContext = "token-context::validate-transitions"
+
+	if state == "" {
+		state = StartEndState
+	}
+
+	trs, err := ctx.StateMachine.FindStateTransitions(state)
+	if err != nil {
+		return nil, err
+	}
+
+	eCtx, err := newTransitionExpressionContext(vars, input)
+	if err != nil {
+		return nil, err
+	}
+
+	tv := TransitionsValidation{State: state, Scope: scope}
+	for _, tr := range trs {
+		if transitionName != "" {
+			if tr.Name != transitionName {
+				continue
+			}
+		} else {
+			ok, _, err := tr.CheckRules(eCtx)
+			if err != nil {
+				log.Warn().Err(err).Str("transition", tr.Name).Msg(semLogContext + " rule evaluation error")
+				continue
+			}
+
+			if !ok {
+				continue
+			}
+		}
+
+		tv.Candidates = append(tv.Candidates, tr.Name)
+		tv.Diagnostics = append(tv.Diagnostics, tr.ValidateProperties(scope, eCtx, input)...)
+	}
+
+	return &tv, nil
+}
+
+func newTransitionExpressionContext(vars ProcessVars, input map[string]interface{}) (*expression.Context, error) {
+	var eOpts []expression.Option
+	if len(vars) > 0 {
+		eOpts = append(eOpts, expression.WithVars(vars))
+	}
+
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+	eOpts = append(eOpts, expression.WithMapInput(input))
+
+	return expression.NewContext(eOpts...)
+}
+
+func evalHelp(eCtx *expression.Context, help CodeDescriptionPair) CodeDescriptionPair {
+	if help.Description == "" {
+		return help
+	}
+
+	v, err := eCtx.EvalOne(help.Description)
+	if err != nil {
+		return help
+	}
+
+	return CodeDescriptionPair{Code: help.Code, Description: fmt.Sprint(v)}
+}
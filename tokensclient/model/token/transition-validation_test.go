@@ -0,0 +1,71 @@
+package token_test
+
+import (
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+var validationTestContext = token.TokenContext{
+	Id: "BPMGM1",
+	StateMachine: token.StateMachine{
+		States: []token.StateDefinition{
+			{
+				Code: token.StartEndState,
+				OutTransitions: []token.Transition{
+					{
+						Name: "creazione",
+						To:   "generato",
+						Properties: []token.Property{
+							{Name: "cf", ValidationRule: token.PropertyValidationRuleRequired, Help: token.CodeDescriptionPair{Code: "cf-req", Description: "il codice fiscale e' obbligatorio"}},
+							{Name: "canale", ValidationRule: token.PropertyValidationRuleRequired, Scope: "next"},
+						},
+					},
+				},
+			},
+			{
+				Code: "generato",
+				OutTransitions: []token.Transition{
+					{
+						Name:       "uso-ok",
+						To:         "bruciato",
+						Properties: []token.Property{{Name: "result", ValidationRule: token.PropertyValidationRuleRequired}},
+						Rules:      []token.Rule{{Expression: "\"{$.result}\" == \"OK\""}},
+					},
+					{
+						Name:  "uso-ko",
+						To:    "generato",
+						Rules: []token.Rule{{Expression: "\"{$.result}\" == \"KO\""}},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestValidateTransitions(t *testing.T) {
+
+	tv, err := validationTestContext.ValidateTransitions("", "", "check", nil, map[string]interface{}{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"creazione"}, tv.Candidates)
+	require.Len(t, tv.Diagnostics, 1)
+	require.Equal(t, "cf-req", tv.Diagnostics[0].Help.Code)
+	require.Error(t, tv.Err())
+
+	tv, err = validationTestContext.ValidateTransitions(token.StartEndState, "", "next", nil, map[string]interface{}{"cf": "MPRMLS62S21G337J"})
+	require.NoError(t, err)
+	require.Len(t, tv.Diagnostics, 1)
+	require.Equal(t, "canale", tv.Diagnostics[0].Property)
+
+	tv, err = validationTestContext.ValidateTransitions("generato", "", "next", nil, map[string]interface{}{"result": "OK"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"uso-ok"}, tv.Candidates)
+	require.True(t, tv.IsValid())
+
+	tv, err = validationTestContext.ValidateTransitions("generato", "", "next", nil, map[string]interface{}{"result": "??"})
+	require.NoError(t, err)
+	require.False(t, tv.IsValid())
+
+	_, err = validationTestContext.ValidateTransitions("unknown", "", "next", nil, nil)
+	require.Error(t, err)
+}
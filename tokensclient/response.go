@@ -14,6 +14,8 @@ const (
 	ErrorDefaultMessage       = ""
 	ServerErrorDefaultMessage = "server error"
 	BadRequestDefaultMessage  = "bad request"
+
+	InvalidTokenIdErrorCode = "invalid-token-id"
 )
 
 type Option func(executableError *ApiResponse)
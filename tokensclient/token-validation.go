@@ -0,0 +1,29 @@
+package tokensclient
+
+import (
+	"github.com/GPA-Gruppo-Progetti-Avanzati-SRL/tpm-tokens-client/tokensclient/model/token"
+)
+
+// ValidateTokenApiRequest validates locally the properties of the request against the transitions that the server would consider
+// for the token. tok is nil for tokens still to be created. transitionName is empty unless the request is meant for TakeTransition.
+func ValidateTokenApiRequest(tokCtx *token.TokenContext, tok *token.Token, transitionName string, tokenRequest *TokenApiRequest) (*token.TransitionsValidation, error) {
+
+	state := token.StartEndState
+	var vars token.ProcessVars
+	if tok != nil && len(tok.Events) > 0 {
+		state = tok.FindCurrentState()
+		vars = tok.Vars()
+	}
+
+	scope := token.EventTypeNext.Scope()
+	if tokenRequest.CheckOnlyFLag {
+		scope = token.EventTypeCheck.Scope()
+	}
+
+	tv, err := tokCtx.ValidateTransitions(state, transitionName, scope, vars, tokenRequest.CustomData)
+	if err != nil {
+		return nil, err
+	}
+
+	return tv, nil
+}